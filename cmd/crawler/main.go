@@ -14,6 +14,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 )
@@ -38,7 +39,65 @@ func main() {
 	defer db.Close()
 
 	store := storage.NewStorage(db)
-	domainMgr := crawler.NewDomainManager(cfg.RateLimit)
+	robotsAgent := cfg.RobotsAgent
+	if robotsAgent == "" {
+		robotsAgent = crawler.RobotsAgentToken(cfg.UserAgent)
+	}
+	log.Printf("User-Agent: %q, robots.txt agent: %q", cfg.UserAgent, robotsAgent)
+	robotsCfg := crawler.RobotsConfig{
+		Agent:        robotsAgent,
+		UserAgent:    cfg.UserAgent,
+		TTL:          cfg.RobotsTTL,
+		ErrorTTL:     cfg.RobotsErrorTTL,
+		MaxSize:      cfg.RobotsMaxSize,
+		MaxRedirects: cfg.RobotsMaxRedirects,
+	}
+	if cfg.RobotsPersist {
+		robotsCfg.Store = &storage.RobotsStore{Storage: store}
+	}
+	grouping, err := crawler.ParseGroupMode(cfg.PolitenessGrouping)
+	if err != nil {
+		log.Fatalf("Invalid POLITENESS_GROUPING: %v", err)
+	}
+	domainMgr := crawler.NewDomainManager(crawler.RateConfig{
+		Delay:         cfg.RateLimit,
+		MinDelay:      cfg.RateMinDelay,
+		MaxDelay:      cfg.RateMaxDelay,
+		LatencyTarget: cfg.RateLatencyTarget,
+		MaxInFlight:   cfg.MaxInFlightPerHost,
+		Grouping:      grouping,
+		GroupDelay:    cfg.GroupRateLimit,
+		DNSCacheTTL:   cfg.DNSCacheTTL,
+	}, robotsCfg, crawler.HostStateConfig{
+		MaxHosts: cfg.HostStateMax,
+		IdleTTL:  cfg.HostStateTTL,
+		Store:    &storage.HostStateStore{Storage: store},
+	})
+
+	domainMgr.SetBreakerConfig(crawler.BreakerConfig{
+		Failures:   cfg.BreakerFailures,
+		CoolOff:    cfg.BreakerCoolOff,
+		MaxCoolOff: cfg.BreakerMaxCoolOff,
+	})
+
+	err = domainMgr.SetDynamicConfig(crawler.DynamicConfig{
+		TTL:             cfg.DynamicTTL,
+		StaticSuccesses: cfg.DynamicStaticSuccesses,
+		Store:           &storage.DynamicStore{Storage: store},
+	})
+	if err != nil {
+		log.Fatalf("Failed to load dynamic-rendering hosts: %v", err)
+	}
+
+	var policies *crawler.PolicySet
+	if cfg.PolicyFile != "" {
+		policies, err = crawler.LoadPolicyFile(cfg.PolicyFile)
+		if err != nil {
+			log.Fatalf("Failed to load domain policies: %v", err)
+		}
+		domainMgr.SetPolicies(policies)
+		log.Printf("Loaded %d domain policies from %s", len(policies.Policies), cfg.PolicyFile)
+	}
 
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", true),
@@ -52,24 +111,164 @@ func main() {
 	// 'allocCtx' is the handle to the browser process.
 	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
 	defer cancelAlloc()
-	parser := crawler.NewParser("MyPageCrawler/1.0", allocCtx, domainMgr)
+	parser := crawler.NewParser(cfg.UserAgent, allocCtx, domainMgr)
+	parser.SetTabPool(crawler.TabPoolConfig{
+		Size:    cfg.ChromeTabs,
+		MaxUses: cfg.ChromeTabMaxUses,
+	})
+	defer parser.Close()
+	parser.Content = crawler.NewContentPolicy(
+		cfg.SkipExtensions, cfg.AllowExtensions, cfg.AllowedContentTypes, cfg.HeadProbe, cfg.MaxContentLength,
+	)
+	parser.Limits = crawler.BodyLimits{
+		MaxRaw:     cfg.MaxBodyBytes,
+		MaxDecoded: cfg.MaxDecodedBytes,
+		Truncate:   cfg.TruncateOversized,
+	}
+	parser.Redirects = crawler.RedirectPolicy{
+		MaxHops:   cfg.MaxRedirects,
+		CrossHost: cfg.RedirectCrossHost,
+	}
+	parser.Waits, err = crawler.ParseWaitConditions(cfg.WaitFor)
+	if err != nil {
+		log.Fatalf("Invalid WAIT_FOR: %v", err)
+	}
+	if cfg.CaptureAPI {
+		parser.Capture, err = crawler.NewAPICapture(cfg.CaptureAPIPatterns, cfg.CaptureAPIJSON, cfg.CaptureAPIMaxBytes, cfg.CaptureAPIMaxPerURL)
+		if err != nil {
+			log.Fatalf("Invalid CAPTURE_API_PATTERNS: %v", err)
+		}
+	}
+
+	rotation, err := crawler.ParseRotation(cfg.ProxyRotation)
+	if err != nil {
+		log.Fatalf("Invalid PROXY_ROTATION: %v", err)
+	}
+	proxies, err := crawler.NewProxyPool(cfg.Proxies, crawler.ProxyPoolConfig{
+		Rotation:   rotation,
+		EjectAfter: cfg.ProxyEjectAfter,
+		EjectFor:   cfg.ProxyEjectFor,
+		MaxSticky:  cfg.HostStateMax,
+	})
+	if err != nil {
+		log.Fatalf("Invalid PROXIES: %v", err)
+	}
+	if proxies != nil {
+		parser.Proxies = proxies
+		domainMgr.SetProxies(proxies)
+		log.Printf("Routing fetches through %d proxies (%s rotation)", proxies.Len(), cfg.ProxyRotation)
+	}
+
+	var cookies *crawler.CookieJar
+	if cfg.CookieJar {
+		var cookieStore crawler.CookieStore
+		if cfg.CookiePersist {
+			cookieStore = &storage.CookieStore{Storage: store}
+		}
+		cookies = crawler.NewCookieJar(cookieStore, cfg.HostStateMax, cfg.HostStateTTL)
+		parser.SetCookieJar(cookies)
+	}
+
+	if cfg.FetchRecordDir != "" {
+		static := &crawler.ReplayFetcher{Dir: filepath.Join(cfg.FetchRecordDir, "static"), Limits: parser.Limits}
+		dynamic := &crawler.ReplayFetcher{Dir: filepath.Join(cfg.FetchRecordDir, "dynamic"), Limits: parser.Limits}
+		if !cfg.FetchReplayOnly {
+			static.Next, dynamic.Next = parser.Static, parser.Dynamic
+		}
+		parser.Static, parser.Dynamic = static, dynamic
+		log.Printf("Recording/replaying fetches in %s (replay only: %v)", cfg.FetchRecordDir, cfg.FetchReplayOnly)
+	}
 
-	// AlwaysFilter: follow links across any domain for maximum spread in stress testing.
-	filter := &crawler.AlwaysFilter{}
+	// Every URL we decide not to queue or fetch ends up in 'crawl_suppressions'.
+	suppressions := &storage.SuppressionLog{Storage: store}
+
+	// AlwaysFilter: follow links across any domain for maximum spread in stress testing,
+	// unless FILTER_FILE narrows the crawl scope.
+	var filter crawler.URLFilter = &crawler.AlwaysFilter{}
+	if cfg.FilterFile != "" {
+		filter, err = crawler.LoadFilterFile(cfg.FilterFile)
+		if err != nil {
+			log.Fatalf("Failed to load filter: %v", err)
+		}
+		log.Printf("Loaded URL filter from %s", cfg.FilterFile)
+	}
+	if policies != nil {
+		filter = crawler.AndFilter{filter, crawler.PolicyFilter{Policies: policies}}
+	}
+	if cfg.ScopeMode != "" {
+		mode, err := crawler.ParseScopeMode(cfg.ScopeMode)
+		if err != nil {
+			log.Fatalf("Invalid SCOPE_MODE: %v", err)
+		}
+		scope, err := crawler.NewSeedScopeFilter(mode, cfg.StartURLs...)
+		if err != nil {
+			log.Fatalf("Failed to build seed scope: %v", err)
+		}
+		filter = crawler.AndFilter{scope, filter}
+	}
+	// Redirects are only followed to where a link would have been.
+	parser.Redirects.Scope = filter
 
 	// 2. Define Strategies for Page Content
 	// Strategy: Parse full content
 	pageProc := &crawler.PageProcessor{
 		Parser: parser,
 		Filter: filter,
+		Compliance: crawler.CompliancePolicy{
+			HonorCanonical:    cfg.HonorCanonical,
+			HonorNoIndex:      cfg.HonorNoIndex,
+			HonorNoFollow:     cfg.HonorNoFollow,
+			HonorLinkNofollow: cfg.HonorLinkNofollow,
+		},
+		Reporter: suppressions,
 	}
 	// Sink: Save to 'pages' table
 	pageSink := &storage.PageSink{Storage: store}
+	if cfg.Revalidate {
+		// Recrawls send the stored ETag/Last-Modified; unchanged pages come back as a bodyless 304,
+		// whose links are then taken from 'page_links'.
+		parser.Validators = pageSink
+		pageProc.Links = pageSink
+	}
+
+	// Trap detection: keep the worklist out of calendars, facets and session-ID loops.
+	var traps *crawler.TrapDetector
+	if cfg.TrapDetection {
+		traps = crawler.NewTrapDetector(crawler.TrapConfig{
+			MaxPathDepth:      cfg.TrapMaxPathDepth,
+			MaxSegmentRepeats: cfg.TrapMaxSegmentRepeats,
+			MaxQueryVariants:  cfg.TrapMaxQueryVariants,
+			MaxURLLength:      cfg.TrapMaxURLLength,
+			MaxHostGrowth:     cfg.TrapMaxHostGrowth,
+			GrowthWindow:      cfg.TrapGrowthWindow,
+			MaxHosts:          cfg.HostStateMax,
+			IdleTTL:           cfg.HostStateTTL,
+		}, suppressions)
+	}
+
+	// Sitemaps: seed each new host's URLs best-first and keep them for coverage reports.
+	var sitemaps *crawler.SitemapDiscoverer
+	if cfg.Sitemaps {
+		sitemaps = crawler.NewSitemapDiscoverer(cfg.UserAgent, cfg.SitemapMaxURLs, domainMgr)
+		sitemaps.Filter = filter
+		sitemaps.Sink = &storage.SitemapSink{Storage: store}
+		if proxies != nil {
+			sitemaps.SetProxies(proxies)
+		}
+	}
 
 	// 3. Initialize Engine with [models.PageData]
 	// Note: We increase BatchSize because page data is larger than product links
 	crawlerEngine := engine.NewEngine[models.PageData](
-		engine.Config{Workers: cfg.Workers, BatchSize: cfg.BatchSize, MaxURLs: cfg.MaxURLs},
+		engine.Config{
+			Workers:           cfg.Workers,
+			BatchSize:         cfg.BatchSize,
+			MaxURLs:           cfg.MaxURLs,
+			Traps:             traps,
+			Sitemaps:          sitemaps,
+			SitemapWorkers:    cfg.SitemapWorkers,
+			DeferOpenCircuits: cfg.BreakerDefer,
+		},
 		pageProc,
 		pageSink,
 		domainMgr,
@@ -83,9 +282,70 @@ func main() {
 		<-stopChan
 		cancel()
 	}()
+	go logThrottledHosts(ctx, domainMgr)
+	go proxies.HealthCheck(ctx, cfg.ProxyCheckURL, cfg.ProxyCheckInterval)
+	go flushCookies(ctx, cookies, cfg.CookieFlushInterval)
+	go flushSuppressions(ctx, suppressions)
 
 	log.Println("Starting Page Content Crawler...")
 	crawlerEngine.Run(ctx, cfg.StartURLs...)
+	cookies.Flush()
+	suppressions.Flush()
+}
+
+// flushSuppressions writes buffered suppressions every few seconds, so quiet
+// crawls don't hold them back until the batch fills up.
+func flushSuppressions(ctx context.Context, suppressions *storage.SuppressionLog) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			suppressions.Flush()
+		}
+	}
+}
+
+// flushCookies periodically saves cookies that changed, so a crash loses at most one interval.
+func flushCookies(ctx context.Context, cookies *crawler.CookieJar, interval time.Duration) {
+	if cookies == nil || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cookies.Flush()
+		}
+	}
+}
+
+// logThrottledHosts periodically reports hosts that are running slower than RATE_LIMIT.
+func logThrottledHosts(ctx context.Context, domainMgr *crawler.DomainManager) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, r := range domainMgr.Rates() {
+				if r.CircuitOpen {
+					log.Printf("[Breaker] %s: circuit open", r.Host)
+				} else if r.Delay > domainMgr.BaseDelay() || time.Now().Before(r.PausedUntil) {
+					log.Printf("[Throttle] %s: 1 request every %s (paused until %s)", r.Host, r.Delay, r.PausedUntil.Format(time.TimeOnly))
+				}
+			}
+		}
+	}
 }
 
 func waitForDB(url string) *sql.DB {
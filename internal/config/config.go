@@ -27,6 +27,155 @@ type Config struct {
 
 	// RateLimit maps to RATE_LIMIT. We can even parse durations directly!
 	RateLimit time.Duration `envconfig:"RATE_LIMIT" default:"2s"`
+
+	// Adaptive rate limiting: hosts slow down on 429/503 (honouring Retry-After) and
+	// speed back up while healthy, but never faster than RATE_MIN_DELAY (0 = RATE_LIMIT).
+	RateMinDelay      time.Duration `envconfig:"RATE_MIN_DELAY" default:"0"`
+	RateMaxDelay      time.Duration `envconfig:"RATE_MAX_DELAY" default:"2m"`
+	RateLatencyTarget time.Duration `envconfig:"RATE_LATENCY_TARGET" default:"3s"`
+
+	// MaxInFlightPerHost caps concurrent requests (including Chrome renders) to one host.
+	MaxInFlightPerHost int `envconfig:"MAX_IN_FLIGHT_PER_HOST" default:"2"`
+
+	// PolitenessGrouping also paces hosts that share an "ip" or "subnet" (/24) together,
+	// at one request per GROUP_RATE_LIMIT (or a policy's group_rate_limit). "host" (default)
+	// turns grouping off.
+	PolitenessGrouping string        `envconfig:"POLITENESS_GROUPING" default:"host"`
+	GroupRateLimit     time.Duration `envconfig:"GROUP_RATE_LIMIT" default:"500ms"`
+	DNSCacheTTL        time.Duration `envconfig:"DNS_CACHE_TTL" default:"10m"`
+
+	// Per-host state (throttles, robots.txt, dynamic rules, trap detection...) is kept for at most
+	// HOST_STATE_MAX hosts per table and dropped after HOST_STATE_TTL without use.
+	HostStateMax int           `envconfig:"HOST_STATE_MAX" default:"100000"`
+	HostStateTTL time.Duration `envconfig:"HOST_STATE_TTL" default:"1h"`
+
+	// Circuit breaker: after BREAKER_FAILURES consecutive failures or blocks (network
+	// errors, 403, 429, 5xx) a host is skipped for BREAKER_COOL_OFF, doubling up to
+	// BREAKER_MAX_COOL_OFF while probes keep failing. 0 failures turns it off.
+	BreakerFailures   int           `envconfig:"BREAKER_FAILURES" default:"5"`
+	BreakerCoolOff    time.Duration `envconfig:"BREAKER_COOL_OFF" default:"1m"`
+	BreakerMaxCoolOff time.Duration `envconfig:"BREAKER_MAX_COOL_OFF" default:"30m"`
+	BreakerDefer      bool          `envconfig:"BREAKER_DEFER" default:"true"`
+
+	// Revalidate sends If-None-Match / If-Modified-Since when recrawling a stored page.
+	Revalidate bool `envconfig:"REVALIDATE" default:"true"`
+
+	// Dynamic rendering memory: hosts marked as needing Chrome are saved to Postgres
+	// and retried with static fetches after DYNAMIC_TTL.
+	DynamicTTL             time.Duration `envconfig:"DYNAMIC_TTL" default:"168h"`
+	DynamicStaticSuccesses int           `envconfig:"DYNAMIC_STATIC_SUCCESSES" default:"3"`
+
+	// Sitemaps seeds the frontier from each host's sitemaps (robots.txt "Sitemap:" or /sitemap.xml).
+	// SITEMAP_WORKERS hosts are read at once, so at most that many times
+	// SITEMAP_MAX_URLS entries are held in memory.
+	Sitemaps       bool `envconfig:"SITEMAPS" default:"true"`
+	SitemapMaxURLs int  `envconfig:"SITEMAP_MAX_URLS" default:"50000"`
+	SitemapWorkers int  `envconfig:"SITEMAP_WORKERS" default:"2"`
+
+	// PolicyFile points to a YAML/JSON file of per-domain overrides (rate, concurrency,
+	// fetch mode, headers, cookies, max pages, allowed paths).
+	PolicyFile string `envconfig:"POLICY_FILE"`
+
+	// FilterFile points to a YAML/JSON URL filter definition (empty = follow every link).
+	FilterFile string `envconfig:"FILTER_FILE"`
+
+	// ScopeMode keeps the crawl near the seeds: "host", "domain" (eTLD+1) or "prefix".
+	// Empty means no scoping. Each START_URLS entry gets its own scope.
+	ScopeMode string `envconfig:"SCOPE_MODE"`
+
+	// Content gating: what we refuse to download before/while fetching.
+	SkipExtensions      []string `envconfig:"SKIP_EXTENSIONS" default:"pdf,zip,gz,tgz,tar,rar,7z,exe,dmg,iso,apk,jpg,jpeg,png,gif,webp,svg,ico,bmp,tif,tiff,mp3,mp4,m4a,avi,mov,mkv,webm,wav,flac,ogg,woff,woff2,ttf,eot,css,js,doc,docx,xls,xlsx,ppt,pptx"`
+	AllowExtensions     []string `envconfig:"ALLOW_EXTENSIONS"`
+	HeadProbe           bool     `envconfig:"HEAD_PROBE" default:"false"`
+	AllowedContentTypes []string `envconfig:"ALLOWED_CONTENT_TYPES" default:"text/html,application/xhtml+xml"`
+	MaxContentLength    int64    `envconfig:"MAX_CONTENT_LENGTH" default:"0"`
+
+	// Response size limits, enforced while reading: MAX_BODY_BYTES on the wire and
+	// MAX_DECODED_BYTES after decompression (or of the rendered HTML). Oversized pages
+	// are truncated (and flagged) if TRUNCATE_OVERSIZED, else skipped. 0 = unlimited.
+	MaxBodyBytes      int64 `envconfig:"MAX_BODY_BYTES" default:"10485760"`
+	MaxDecodedBytes   int64 `envconfig:"MAX_DECODED_BYTES" default:"20971520"`
+	TruncateOversized bool  `envconfig:"TRUNCATE_OVERSIZED" default:"true"`
+
+	// Redirects: at most MAX_REDIRECTS hops, and only within the same site
+	// (registrable domain) unless REDIRECT_CROSS_HOST. Pages are stored under the
+	// URL the redirects end at.
+	MaxRedirects      int  `envconfig:"MAX_REDIRECTS" default:"10"`
+	RedirectCrossHost bool `envconfig:"REDIRECT_CROSS_HOST" default:"false"`
+
+	// Proxies routes static fetches and Chrome through a pool of http:// or socks5://
+	// proxies (empty = direct). PROXY_ROTATION is round-robin, sticky (per host) or
+	// health (weighted by success rate). A proxy failing PROXY_EJECT_AFTER times in a
+	// row sits out PROXY_EJECT_FOR, or until it passes a GET of PROXY_CHECK_URL.
+	Proxies            []string      `envconfig:"PROXIES"`
+	ProxyRotation      string        `envconfig:"PROXY_ROTATION" default:"round-robin"`
+	ProxyEjectAfter    int           `envconfig:"PROXY_EJECT_AFTER" default:"5"`
+	ProxyEjectFor      time.Duration `envconfig:"PROXY_EJECT_FOR" default:"5m"`
+	ProxyCheckURL      string        `envconfig:"PROXY_CHECK_URL"`
+	ProxyCheckInterval time.Duration `envconfig:"PROXY_CHECK_INTERVAL" default:"1m"`
+
+	// Chrome: renders share one browser and up to CHROME_TABS warm tabs, each reset
+	// between pages and replaced after CHROME_TAB_MAX_USES renders or a crash.
+	ChromeTabs       int `envconfig:"CHROME_TABS" default:"4"`
+	ChromeTabMaxUses int `envconfig:"CHROME_TAB_MAX_USES" default:"50"`
+
+	// WaitFor decides when a render is done: ';'-separated conditions, the first one
+	// met ends the wait. Each is kind[=arg][@timeout] with kind network-idle (=quiet
+	// period), domcontentloaded, load, selector (=CSS) or js (=expression).
+	// Empty = the load event. Domain policies can override it with 'wait'.
+	WaitFor string `envconfig:"WAIT_FOR" default:"load@30s"`
+
+	// API capture: with CAPTURE_API, the XHR/fetch responses of a render whose URL
+	// matches one of CAPTURE_API_PATTERNS (regexes), or that are JSON when
+	// CAPTURE_API_JSON is set, are stored with the page in api_responses.
+	CaptureAPI          bool     `envconfig:"CAPTURE_API" default:"false"`
+	CaptureAPIPatterns  []string `envconfig:"CAPTURE_API_PATTERNS"`
+	CaptureAPIJSON      bool     `envconfig:"CAPTURE_API_JSON" default:"true"`
+	CaptureAPIMaxBytes  int64    `envconfig:"CAPTURE_API_MAX_BYTES" default:"1048576"`
+	CaptureAPIMaxPerURL int      `envconfig:"CAPTURE_API_MAX" default:"50"`
+
+	// Cookies: a per-site jar shared by static fetches and Chrome renders, so consent
+	// and session cookies set on the first visit are sent on the next ones. With
+	// COOKIE_PERSIST it is saved every COOKIE_FLUSH_INTERVAL and reloaded on restart.
+	CookieJar           bool          `envconfig:"COOKIE_JAR" default:"true"`
+	CookiePersist       bool          `envconfig:"COOKIE_PERSIST" default:"true"`
+	CookieFlushInterval time.Duration `envconfig:"COOKIE_FLUSH_INTERVAL" default:"1m"`
+
+	// Record/replay: with FETCH_RECORD_DIR every fetched response is saved there and
+	// served from disk on later runs. FETCH_REPLAY_ONLY never fetches a page from
+	// the network, so URLs without a recording fail; robots.txt, sitemaps and the
+	// HEAD probe before a render are still fetched.
+	FetchRecordDir  string `envconfig:"FETCH_RECORD_DIR"`
+	FetchReplayOnly bool   `envconfig:"FETCH_REPLAY_ONLY" default:"false"`
+
+	// Compliance: which robots directives found on the page itself are enforced.
+	HonorCanonical    bool `envconfig:"HONOR_CANONICAL" default:"true"`
+	HonorNoIndex      bool `envconfig:"HONOR_NOINDEX" default:"true"`
+	HonorNoFollow     bool `envconfig:"HONOR_NOFOLLOW" default:"true"`
+	HonorLinkNofollow bool `envconfig:"HONOR_LINK_NOFOLLOW" default:"true"`
+
+	// UserAgent is sent on every static fetch.
+	UserAgent string `envconfig:"USER_AGENT" default:"MyPageCrawler/1.0"`
+
+	// RobotsAgent is the robots.txt token we obey. Empty = derived from USER_AGENT.
+	RobotsAgent string `envconfig:"ROBOTS_AGENT"`
+
+	// robots.txt fetching and caching (RFC 9309).
+	RobotsTTL          time.Duration `envconfig:"ROBOTS_TTL" default:"24h"`
+	RobotsErrorTTL     time.Duration `envconfig:"ROBOTS_ERROR_TTL" default:"10m"`
+	RobotsMaxSize      int64         `envconfig:"ROBOTS_MAX_SIZE" default:"512000"`
+	RobotsMaxRedirects int           `envconfig:"ROBOTS_MAX_REDIRECTS" default:"5"`
+	RobotsPersist      bool          `envconfig:"ROBOTS_PERSIST" default:"false"`
+
+	// Trap detection thresholds. Set TRAP_DETECTION=false to turn it off entirely;
+	// a zero value for any single limit disables just that heuristic.
+	TrapDetection         bool          `envconfig:"TRAP_DETECTION" default:"true"`
+	TrapMaxPathDepth      int           `envconfig:"TRAP_MAX_PATH_DEPTH" default:"15"`
+	TrapMaxSegmentRepeats int           `envconfig:"TRAP_MAX_SEGMENT_REPEATS" default:"2"`
+	TrapMaxQueryVariants  int           `envconfig:"TRAP_MAX_QUERY_VARIANTS" default:"100"`
+	TrapMaxURLLength      int           `envconfig:"TRAP_MAX_URL_LENGTH" default:"2048"`
+	TrapMaxHostGrowth     int           `envconfig:"TRAP_MAX_HOST_GROWTH" default:"2000"`
+	TrapGrowthWindow      time.Duration `envconfig:"TRAP_GROWTH_WINDOW" default:"1m"`
 }
 
 // Load processes environment variables and populates the Config struct.
@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"go-crawler/pkg/models"
+	"mime"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// APICapture decides which XHR/fetch responses of a render are kept on the page,
+// so processors can read the JSON a page is built from instead of its HTML.
+type APICapture struct {
+	Patterns     []*regexp.Regexp // Capture responses whose URL matches one of these
+	AnyJSON      bool             // Also capture every response with a JSON content type
+	MaxBytes     int64            // Larger bodies are dropped (0 = unlimited)
+	MaxResponses int              // Per page; later ones are dropped (0 = unlimited)
+}
+
+// NewAPICapture compiles the URL patterns (regexes).
+func NewAPICapture(patterns []string, anyJSON bool, maxBytes int64, maxResponses int) (*APICapture, error) {
+	c := &APICapture{AnyJSON: anyJSON, MaxBytes: maxBytes, MaxResponses: maxResponses}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("capture pattern %q: %w", pattern, err)
+		}
+		c.Patterns = append(c.Patterns, re)
+	}
+	return c, nil
+}
+
+func (c *APICapture) matches(url, mimeType string) bool {
+	for _, re := range c.Patterns {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+	return c.AnyJSON && isJSONType(mimeType)
+}
+
+// isJSONType accepts application/json and the +json family (application/ld+json...).
+func isJSONType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// apiRecorder collects the matching responses of one render. A nil recorder
+// (capture off) ignores everything.
+type apiRecorder struct {
+	capture *APICapture
+
+	mu       sync.Mutex
+	methods  map[network.RequestID]string
+	pending  map[network.RequestID]*models.APIResponse // Headers received, body still loading
+	finished []network.RequestID
+	captured []*models.APIResponse
+}
+
+func (c *APICapture) recorder() *apiRecorder {
+	if c == nil {
+		return nil
+	}
+	return &apiRecorder{
+		capture: c,
+		methods: make(map[network.RequestID]string),
+		pending: make(map[network.RequestID]*models.APIResponse),
+	}
+}
+
+func isAPIRequest(t network.ResourceType) bool {
+	return t == network.ResourceTypeXHR || t == network.ResourceTypeFetch
+}
+
+// listen is a ListenTarget handler. Bodies can't be fetched from inside it (that
+// would block the event loop), so it only notes which requests to collect.
+func (r *apiRecorder) listen(ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if isAPIRequest(e.Type) {
+			r.methods[e.RequestID] = e.Request.Method
+		}
+	case *network.EventResponseReceived:
+		if !isAPIRequest(e.Type) || !r.capture.matches(e.Response.URL, e.Response.MimeType) {
+			return
+		}
+		r.pending[e.RequestID] = &models.APIResponse{
+			URL:         e.Response.URL,
+			Method:      r.methods[e.RequestID],
+			StatusCode:  int(e.Response.Status),
+			ContentType: e.Response.MimeType,
+		}
+	case *network.EventLoadingFinished:
+		if _, ok := r.pending[e.RequestID]; ok {
+			r.finished = append(r.finished, e.RequestID)
+		}
+	}
+}
+
+// collect fetches the bodies of the finished responses, in the order they finished.
+// Responses still loading when the render ends are not waited for.
+func (r *apiRecorder) collect() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if r == nil {
+			return nil
+		}
+		r.mu.Lock()
+		finished := append([]network.RequestID(nil), r.finished...)
+		r.mu.Unlock()
+
+		for _, id := range finished {
+			if r.capture.MaxResponses > 0 && len(r.captured) >= r.capture.MaxResponses {
+				break
+			}
+			body, err := r.body(ctx, id)
+			if err != nil || (r.capture.MaxBytes > 0 && int64(len(body)) > r.capture.MaxBytes) {
+				continue // Evicted from Chrome's buffer, binary or too big: not worth failing the render
+			}
+			r.mu.Lock()
+			resp := r.pending[id]
+			r.mu.Unlock()
+			resp.Body = body
+			r.captured = append(r.captured, resp)
+		}
+		return nil
+	})
+}
+
+// body fetches a response body from Chrome. Only text is kept, since the
+// point is to read it as JSON.
+func (r *apiRecorder) body(ctx context.Context, id network.RequestID) (string, error) {
+	body, err := network.GetResponseBody(id).Do(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(body) || strings.ContainsRune(string(body), 0) {
+		return "", fmt.Errorf("binary body")
+	}
+	return string(body), nil
+}
+
+// responses returns what collect captured.
+func (r *apiRecorder) responses() []models.APIResponse {
+	if r == nil {
+		return nil
+	}
+	out := make([]models.APIResponse, len(r.captured))
+	for i, resp := range r.captured {
+		out[i] = *resp
+	}
+	return out
+}
+
+// listenAPI registers the recorder on the render's context, if capture is on.
+func listenAPI(ctx context.Context, r *apiRecorder) {
+	if r != nil {
+		chromedp.ListenTarget(ctx, r.listen)
+	}
+}
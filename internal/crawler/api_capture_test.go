@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/network"
+	"testing"
+)
+
+func TestAPICapture_Matches(t *testing.T) {
+	c, err := NewAPICapture([]string{`/graphql$`}, true, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		url, mimeType string
+		want          bool
+	}{
+		{"https://shop.example/graphql", "text/plain", true},
+		{"https://shop.example/api/items", "application/json; charset=utf-8", true},
+		{"https://shop.example/ld", "application/ld+json", true},
+		{"https://shop.example/pixel", "image/gif", false},
+	}
+	for _, tc := range cases {
+		if got := c.matches(tc.url, tc.mimeType); got != tc.want {
+			t.Errorf("matches(%q, %q) = %v, want %v", tc.url, tc.mimeType, got, tc.want)
+		}
+	}
+
+	c.AnyJSON = false
+	if c.matches("https://shop.example/api/items", "application/json") {
+		t.Error("Expected JSON outside the patterns to be skipped without AnyJSON")
+	}
+	if _, err := NewAPICapture([]string{"("}, false, 0, 0); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+func TestAPIRecorder_TracksFinishedAPIResponses(t *testing.T) {
+	c, _ := NewAPICapture(nil, true, 0, 0)
+	r := c.recorder()
+	events := []interface{}{
+		&network.EventRequestWillBeSent{RequestID: "1", Type: network.ResourceTypeFetch, Request: &network.Request{Method: "POST"}},
+		&network.EventResponseReceived{RequestID: "1", Type: network.ResourceTypeFetch, Response: &network.Response{URL: "https://a.example/api", Status: 200, MimeType: "application/json"}},
+		// A JSON document that is not an XHR/fetch is not an API call.
+		&network.EventResponseReceived{RequestID: "2", Type: network.ResourceTypeDocument, Response: &network.Response{URL: "https://a.example/data.json", MimeType: "application/json"}},
+		&network.EventResponseReceived{RequestID: "3", Type: network.ResourceTypeXHR, Response: &network.Response{URL: "https://a.example/slow", MimeType: "application/json"}},
+		&network.EventLoadingFinished{RequestID: "1"},
+		&network.EventLoadingFinished{RequestID: "2"},
+	}
+	for _, ev := range events {
+		r.listen(ev)
+	}
+
+	if len(r.finished) != 1 || r.finished[0] != "1" {
+		t.Fatalf("Expected only the finished fetch to be collected, got %v", r.finished)
+	}
+	if resp := r.pending["1"]; resp.Method != "POST" || resp.StatusCode != 200 || resp.URL != "https://a.example/api" {
+		t.Errorf("Unexpected captured response %+v", resp)
+	}
+}
+
+func TestAPIRecorder_NilIsOff(t *testing.T) {
+	var c *APICapture
+	r := c.recorder()
+	if err := r.collect().Do(context.Background()); err != nil || r.responses() != nil {
+		t.Errorf("Expected a nil recorder to capture nothing, got %v (%v)", r.responses(), err)
+	}
+}
@@ -0,0 +1,142 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BodyLimits caps how much of one response a worker holds in memory. Zero means unlimited.
+type BodyLimits struct {
+	MaxRaw     int64 // Bytes on the wire, before decompression
+	MaxDecoded int64 // Bytes after decompression (or rendered HTML); this is what stops a gzip bomb
+	Truncate   bool  // Keep the first MaxDecoded bytes of an oversized page instead of rejecting it
+}
+
+var errRawLimit = errors.New("raw body limit exceeded")
+
+// decode wraps a response body so that reads yield decompressed bytes and fail
+// with errRawLimit once more than MaxRaw compressed bytes have been read.
+func (l BodyLimits) decode(body io.ReadCloser, contentEncoding string) (io.ReadCloser, error) {
+	var raw io.Reader = body
+	if l.MaxRaw > 0 {
+		raw = &rawLimitReader{r: body, remaining: l.MaxRaw}
+	}
+
+	var decoded io.Reader
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		decoded = raw
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(raw)
+		switch {
+		case errors.Is(err, io.EOF):
+			decoded = raw // No body at all (e.g. a 304 that still names its encoding)
+		case err != nil:
+			return nil, fmt.Errorf("gzip body: %w", err)
+		default:
+			decoded = gz
+		}
+	case "deflate":
+		// Meant to be zlib-wrapped, but plenty of servers send raw deflate.
+		br := bufio.NewReader(raw)
+		header, _ := br.Peek(2)
+		switch {
+		case len(header) == 0:
+			decoded = br // No body at all (e.g. a 304 that still names its encoding)
+		case isZlibHeader(header):
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, fmt.Errorf("deflate body: %w", err)
+			}
+			decoded = zr
+		default:
+			decoded = flate.NewReader(br)
+		}
+	default:
+		// We only advertise gzip and deflate; hand anything else over as-is.
+		decoded = raw
+	}
+	return readCloser{Reader: decoded, Closer: body}, nil
+}
+
+// isZlibHeader reports whether b starts a zlib stream (RFC 1950): deflate as the
+// compression method and a header checksum that is a multiple of 31.
+func isZlibHeader(b []byte) bool {
+	return len(b) >= 2 && b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
+
+// read reads a (decoded) body within the limits. An oversized body is cut to
+// MaxDecoded and reported as truncated, or rejected with a *SkipError.
+func (l BodyLimits) read(targetURL string, body io.Reader) (data []byte, truncated bool, err error) {
+	r := body
+	if l.MaxDecoded > 0 {
+		r = io.LimitReader(body, l.MaxDecoded+1)
+	}
+	data, err = io.ReadAll(r)
+	rawExceeded := errors.Is(err, errRawLimit)
+	if err != nil && !rawExceeded {
+		return nil, false, err
+	}
+
+	decodedExceeded := l.MaxDecoded > 0 && int64(len(data)) > l.MaxDecoded
+	switch {
+	case !rawExceeded && !decodedExceeded:
+		return data, false, nil
+	case !l.Truncate && rawExceeded:
+		return nil, false, &SkipError{URL: targetURL, Reason: fmt.Sprintf("response exceeds %d bytes", l.MaxRaw)}
+	case !l.Truncate:
+		return nil, false, &SkipError{URL: targetURL, Reason: fmt.Sprintf("decoded response exceeds %d bytes", l.MaxDecoded)}
+	}
+	if decodedExceeded {
+		data = data[:l.MaxDecoded]
+	}
+	return data, true, nil
+}
+
+// fetchRendered renders targetURL with the dynamic backend and applies the
+// decoded limit to the HTML.
+func (p *Parser) fetchRendered(ctx context.Context, targetURL string) (*FetchResponse, bool, error) {
+	resp, err := p.Dynamic.Fetch(FetchRequest{URL: targetURL, Ctx: ctx})
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	html, truncated, err := p.Limits.read(targetURL, resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(html))
+	return resp, truncated || resp.Truncated, nil
+}
+
+// rawLimitReader is io.LimitReader that fails instead of returning EOF when the
+// underlying reader has more to give, so a cut body isn't mistaken for a whole one.
+type rawLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *rawLimitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		if n, err := l.r.Read(probe[:]); n == 0 && err != nil {
+			return 0, err
+		}
+		return 0, errRawLimit
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimits_Read(t *testing.T) {
+	page := strings.Repeat("a", 100)
+
+	data, truncated, err := BodyLimits{MaxRaw: 100, MaxDecoded: 100}.read("u", strings.NewReader(page))
+	if err != nil || truncated || len(data) != 100 {
+		t.Errorf("A body exactly at the limit should pass, got %d bytes, truncated=%v, err=%v", len(data), truncated, err)
+	}
+
+	data, truncated, err = BodyLimits{MaxDecoded: 40, Truncate: true}.read("u", strings.NewReader(page))
+	if err != nil || !truncated || len(data) != 40 {
+		t.Errorf("Expected truncation to 40 bytes, got %d bytes, truncated=%v, err=%v", len(data), truncated, err)
+	}
+
+	var skipped *SkipError
+	if _, _, err = (BodyLimits{MaxDecoded: 40}).read("u", strings.NewReader(page)); !errors.As(err, &skipped) {
+		t.Errorf("Expected an oversized body to be rejected, got %v", err)
+	}
+}
+
+func TestBodyLimits_GzipBomb(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(bytes.Repeat([]byte{0}, 10<<20)) // 10 MiB of zeros, ~10 KiB compressed
+	gz.Close()
+
+	limits := BodyLimits{MaxRaw: 1 << 20, MaxDecoded: 1 << 20}
+	body, err := limits.decode(io.NopCloser(&compressed), "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var skipped *SkipError
+	if _, _, err := limits.read("u", body); !errors.As(err, &skipped) || !strings.Contains(skipped.Reason, "decoded") {
+		t.Errorf("Expected the bomb to trip the decoded limit, got %v", err)
+	}
+}
+
+func TestBodyLimits_DeflateWithAndWithoutZlib(t *testing.T) {
+	page := strings.Repeat("deflated page ", 50)
+
+	var wrapped, raw bytes.Buffer
+	zw := zlib.NewWriter(&wrapped)
+	zw.Write([]byte(page))
+	zw.Close()
+	fw, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	fw.Write([]byte(page))
+	fw.Close()
+
+	tests := []struct {
+		name string
+		body *bytes.Buffer
+		want string
+	}{
+		{"zlib", &wrapped, page},
+		{"raw", &raw, page},
+		{"empty", &bytes.Buffer{}, ""},
+	}
+	for _, tt := range tests {
+		decoded, err := BodyLimits{}.decode(io.NopCloser(tt.body), "deflate")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if data, err := io.ReadAll(decoded); err != nil || string(data) != tt.want {
+			t.Errorf("%s: got %q, %v", tt.name, data, err)
+		}
+	}
+}
+
+func TestParser_ParseTruncatesLargePages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			gz.Write([]byte("<html><body><p>" + strings.Repeat("word ", 2000) + "</p></body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	p.Limits = BodyLimits{MaxDecoded: 1000, Truncate: true}
+
+	data, err := p.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.Truncated || !strings.HasPrefix(data.TextContent, "word") || len(data.TextContent) > 1000 {
+		t.Errorf("Expected a truncated, decompressed page, got truncated=%v and %d chars", data.Truncated, len(data.TextContent))
+	}
+}
+
+func TestScriptOuterHTML(t *testing.T) {
+	if got := scriptOuterHTML(0); strings.Contains(got, "substring") {
+		t.Errorf("Expected the whole DOM without a limit, got %s", got)
+	}
+	// One character past the limit, so an oversized page is still recognised as such.
+	if got := scriptOuterHTML(1000); !strings.Contains(got, "substring(0, 1001)") {
+		t.Errorf("Expected the DOM to be cut in the page, got %s", got)
+	}
+}
@@ -0,0 +1,165 @@
+package crawler
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// BreakerConfig controls the per-host circuit breaker. A host that keeps failing
+// or blocking us is skipped for a cool-off period instead of eating a fetch
+// timeout for every queued URL.
+type BreakerConfig struct {
+	Failures     int           // Consecutive failures that open the circuit (0 = breaker off)
+	CoolOff      time.Duration // How long the circuit stays open the first time
+	MaxCoolOff   time.Duration // Cool-off doubles on every failed probe, up to this
+	ProbeTimeout time.Duration // A probe that never reports back frees the slot after this
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.CoolOff <= 0 {
+		c.CoolOff = time.Minute
+	}
+	if c.MaxCoolOff < c.CoolOff {
+		c.MaxCoolOff = max(c.CoolOff, 30*time.Minute)
+	}
+	if c.ProbeTimeout <= 0 {
+		c.ProbeTimeout = 2 * time.Minute
+	}
+	return c
+}
+
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // Normal operation
+	breakerOpen                         // Failing: everything is turned away until openUntil
+	breakerHalfOpen                     // One probe request is out; everyone else waits for its result
+)
+
+// hostBreaker is the circuit breaker state of one host. It lives on the host's
+// throttle, so it is bounded and evicted together with it.
+type hostBreaker struct {
+	state      breakerState
+	failures   int
+	coolOff    time.Duration
+	openUntil  time.Time
+	probeUntil time.Time
+	probe      uint64 // Incremented for every probe let through, to tell them apart
+}
+
+// isBreakerFailure reports whether a fetch outcome means the host is down or
+// blocking us. statusCode 0 = network error or failed render.
+func isBreakerFailure(statusCode int) bool {
+	return statusCode == 0 || statusCode >= 500 ||
+		statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests
+}
+
+// allow reports whether a request may go out now, or how long to wait before
+// asking again. Once the cool-off is over, exactly one caller gets through as the
+// probe; probe is then its number, 0 otherwise.
+func (b *hostBreaker) allow(cfg BreakerConfig) (wait time.Duration, ok bool, probe uint64) {
+	now := time.Now()
+	switch b.state {
+	case breakerOpen:
+		if now.Before(b.openUntil) {
+			return b.openUntil.Sub(now), false, 0
+		}
+	case breakerHalfOpen:
+		if now.Before(b.probeUntil) {
+			return b.probeUntil.Sub(now), false, 0
+		}
+	default:
+		return 0, true, 0
+	}
+	b.state = breakerHalfOpen
+	b.probeUntil = now.Add(cfg.ProbeTimeout)
+	b.probe++
+	return 0, true, b.probe
+}
+
+// release gives back a probe that was let through but never fetched anything, so
+// the next caller can probe right away instead of waiting out ProbeTimeout.
+func (b *hostBreaker) release(probe uint64) {
+	if probe != 0 && b.state == breakerHalfOpen && b.probe == probe {
+		b.state, b.openUntil = breakerOpen, time.Now()
+	}
+}
+
+// record updates the breaker with one fetch outcome and reports whether it changed state.
+func (b *hostBreaker) record(cfg BreakerConfig, statusCode int) (changed bool) {
+	if !isBreakerFailure(statusCode) {
+		b.failures = 0
+		if b.state == breakerClosed {
+			return false
+		}
+		b.state, b.coolOff = breakerClosed, 0
+		return true
+	}
+
+	switch b.state {
+	case breakerClosed:
+		b.failures++
+		if b.failures < cfg.Failures {
+			return false
+		}
+		b.coolOff = cfg.CoolOff
+	case breakerHalfOpen:
+		b.coolOff = min(b.coolOff*2, cfg.MaxCoolOff)
+	default:
+		// Stragglers that were in flight when the circuit opened.
+		return false
+	}
+	b.state = breakerOpen
+	b.openUntil = time.Now().Add(b.coolOff)
+	return true
+}
+
+// SetBreakerConfig turns on the per-host circuit breaker. Call it before crawling starts.
+func (d *DomainManager) SetBreakerConfig(cfg BreakerConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breaker = cfg.withDefaults()
+}
+
+// CircuitOpen reports whether the link's host is being skipped because it keeps
+// failing, and how long until it is worth trying again. When it returns false the
+// link may be the host's probe: the caller must call release as soon as it knows
+// the link won't be fetched after all, and once the fetch is done (a no-op if the
+// fetch already reported its result), so the probe slot is never left hanging.
+func (d *DomainManager) CircuitOpen(link string) (release func(), retryIn time.Duration, open bool) {
+	noop := func() {}
+	if d.breaker.Failures <= 0 {
+		return noop, 0, false
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return noop, 0, false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	throttle := d.throttleFor(u.Host)
+	wait, ok, probe := throttle.breaker.allow(d.breaker)
+	if probe == 0 {
+		return noop, wait, !ok
+	}
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		throttle.breaker.release(probe)
+	}, 0, false
+}
+
+// recordBreaker feeds a fetch outcome into the host's breaker. Callers must hold d.mu.
+func (d *DomainManager) recordBreaker(host string, throttle *hostThrottle, statusCode int) {
+	if d.breaker.Failures <= 0 || !throttle.breaker.record(d.breaker, statusCode) {
+		return
+	}
+	if throttle.breaker.state == breakerOpen {
+		log.Printf("[Breaker] %s is failing (last status %d), skipping it for %s", host, statusCode, throttle.breaker.coolOff)
+	} else {
+		log.Printf("[Breaker] %s is healthy again", host)
+	}
+}
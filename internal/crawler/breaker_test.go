@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDomainManager_CircuitBreaker(t *testing.T) {
+	d := NewDomainManager(RateConfig{}, RobotsConfig{}, HostStateConfig{})
+	d.SetBreakerConfig(BreakerConfig{Failures: 3, CoolOff: 20 * time.Millisecond, MaxCoolOff: time.Second})
+	link := "https://down.com/page"
+
+	// 404s mean the host is up; only failures and blocks count.
+	d.RecordResult(link, http.StatusNotFound, 0, nil)
+	d.RecordResult(link, 0, 0, nil)
+	d.RecordResult(link, http.StatusForbidden, 0, nil)
+	if _, _, open := d.CircuitOpen(link); open {
+		t.Fatal("Circuit should stay closed below the failure threshold")
+	}
+	d.RecordResult(link, http.StatusBadGateway, 0, nil)
+	if _, retryIn, open := d.CircuitOpen(link); !open || retryIn <= 0 {
+		t.Fatalf("Expected the circuit to open after 3 failures, got open=%v retryIn=%s", open, retryIn)
+	}
+
+	// After the cool-off exactly one probe goes through.
+	time.Sleep(30 * time.Millisecond)
+	if _, _, open := d.CircuitOpen(link); open {
+		t.Fatal("Expected a probe after the cool-off")
+	}
+	if _, _, open := d.CircuitOpen(link); !open {
+		t.Fatal("Only one probe should be let through")
+	}
+
+	// A failed probe re-opens it for twice as long.
+	d.RecordResult(link, 0, 0, nil)
+	if _, retryIn, open := d.CircuitOpen(link); !open || retryIn <= 20*time.Millisecond {
+		t.Fatalf("Expected a longer cool-off after a failed probe, got open=%v retryIn=%s", open, retryIn)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, _, open := d.CircuitOpen(link); open {
+		t.Fatal("Expected a second probe")
+	}
+	d.RecordResult(link, http.StatusOK, 0, nil)
+	if _, _, open := d.CircuitOpen(link); open {
+		t.Error("A successful probe should close the circuit")
+	}
+	if _, _, open := d.CircuitOpen("https://other.com/"); open {
+		t.Error("Other hosts must not be affected")
+	}
+}
+
+func TestDomainManager_UnusedProbeIsReleased(t *testing.T) {
+	d := NewDomainManager(RateConfig{}, RobotsConfig{}, HostStateConfig{})
+	d.SetBreakerConfig(BreakerConfig{Failures: 1, CoolOff: 10 * time.Millisecond, ProbeTimeout: time.Hour})
+	link := "https://down.com/page"
+	d.RecordResult(link, 0, 0, nil)
+	time.Sleep(20 * time.Millisecond)
+
+	release, _, open := d.CircuitOpen(link)
+	if open {
+		t.Fatal("Expected a probe after the cool-off")
+	}
+	if _, _, open := d.CircuitOpen(link); !open {
+		t.Fatal("Only one probe should be let through")
+	}
+	// The probe turned out to be a duplicate (or was skipped): nothing was fetched.
+	release()
+	release2, _, open := d.CircuitOpen(link)
+	if open {
+		t.Fatal("Expected a released probe to let the next caller probe right away")
+	}
+
+	// Once the probe's fetch has reported, releasing it changes nothing.
+	d.RecordResult(link, http.StatusOK, 0, nil)
+	release2()
+	if _, _, open := d.CircuitOpen(link); open {
+		t.Error("A successful probe should close the circuit")
+	}
+}
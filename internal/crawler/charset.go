@@ -0,0 +1,22 @@
+package crawler
+
+import (
+	"golang.org/x/net/html/charset"
+)
+
+// toUTF8 transcodes a static response body to UTF-8 for html.Parse. The encoding
+// comes from (in order) a BOM, the Content-Type header, a <meta charset> or
+// http-equiv in the first 1024 bytes, and finally sniffing (UTF-8 if the body is
+// valid UTF-8, else windows-1252). It returns the canonical charset name.
+func toUTF8(body []byte, contentType string) ([]byte, string) {
+	encoding, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" {
+		return body, name
+	}
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		// Leave it to html.Parse rather than lose the page.
+		return body, name
+	}
+	return decoded, name
+}
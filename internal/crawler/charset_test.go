@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"strings"
+	"testing"
+)
+
+func TestToUTF8(t *testing.T) {
+	cyrillic, _ := charmap.Windows1251.NewEncoder().String("<html><body>Привет, мир</body></html>")
+	japaneseMeta, _ := japanese.ShiftJIS.NewEncoder().String(`<html><head><meta charset="Shift_JIS"></head><body>こんにちは</body></html>`)
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		charset     string
+		want        string
+	}{
+		{"Header", cyrillic, "text/html; charset=windows-1251", "windows-1251", "Привет, мир"},
+		{"MetaCharset", japaneseMeta, "text/html", "shift_jis", "こんにちは"},
+		{"Latin1Header", "<p>Caf\xe9</p>", "text/html; charset=ISO-8859-1", "windows-1252", "Café"},
+		{"UTF8BOM", "\xef\xbb\xbf<p>naïve</p>", "text/html; charset=windows-1251", "utf-8", "naïve"},
+		{"SniffedUTF8", "<p>déjà vu</p>", "", "utf-8", "déjà vu"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, name := toUTF8([]byte(tt.body), tt.contentType)
+			if name != tt.charset {
+				t.Errorf("Expected charset %q, got %q", tt.charset, name)
+			}
+			if !strings.Contains(string(decoded), tt.want) {
+				t.Errorf("Expected %q in the decoded body, got %q", tt.want, decoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"go-crawler/pkg/models"
+	"io"
+	"log"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Removes the "I am a robot" flag
+	scriptStealth = `
+		Object.defineProperty(navigator, 'webdriver', { get: () => undefined });
+		window.navigator.chrome = { runtime: {} };
+	`
+	// Forces all links into a clean list for the Go parser
+	scriptLinkShim = `(function(){
+		window.scrollTo(0, document.body.scrollHeight);
+		const links = document.querySelectorAll('a[href]');
+		const shim = document.createElement('div');
+		shim.id = 'crawler-shim';
+		shim.style.display = 'none';
+		links.forEach(l => {
+			const a = document.createElement('a');
+			a.href = l.href;
+			a.innerText = 'shim-link';
+			shim.appendChild(a);
+		});
+		document.body.appendChild(shim);
+	})()`
+)
+
+// scriptOuterHTML returns the rendered document's HTML. With a limit, only the
+// first limit+1 characters are transferred: enough for BodyLimits to tell the
+// page is oversized (a character is at least one byte) and cut or reject it.
+func scriptOuterHTML(limit int64) string {
+	if limit <= 0 {
+		return `document.documentElement.outerHTML`
+	}
+	return fmt.Sprintf(`document.documentElement.outerHTML.substring(0, %d)`, limit+1)
+}
+
+type BrowserProfile struct {
+	UserAgent  string
+	ClientHint string
+}
+
+// Valid Linux Profiles (Matches your Docker Container)
+var linuxProfiles = []BrowserProfile{
+	// Profile 1: Chrome 132 (Latest)
+	{
+		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36",
+		ClientHint: `"Not A(Brand";v="99", "Google Chrome";v="132", "Chromium";v="132"`,
+	},
+	// Profile 2: Chrome 131
+	{
+		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+		ClientHint: `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
+	},
+	// Profile 3: Chrome 130
+	{
+		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36",
+		ClientHint: `"Chromium";v="130", "Google Chrome";v="130", "Not?A_Brand";v="99"`,
+	},
+}
+
+func getRandomProfile() BrowserProfile {
+	return linuxProfiles[rand.Intn(len(linuxProfiles))]
+}
+
+// chromeFetcher is the default dynamic backend: it renders the page in a tab
+// borrowed from the Parser's TabPool. The reported redirects include client-side
+// ones (meta refresh, JS) that end in the final URL.
+type chromeFetcher struct {
+	p *Parser
+}
+
+func (f *chromeFetcher) Fetch(req FetchRequest) (*FetchResponse, error) {
+	p, targetURL := f.p, req.URL
+	profile := getRandomProfile()
+	finalURL := targetURL
+	var hops []models.Redirect
+
+	// A proxy is a per-BrowserContext setting, so the tab has to be one set up for it.
+	var proxy *url.URL
+	if u, err := url.Parse(targetURL); err == nil {
+		proxy = p.Proxies.Pick(u.Host)
+	}
+	tab, err := p.tabs.acquire(proxy)
+	if err != nil {
+		return nil, err
+	}
+	healthy := false
+	defer func() { p.tabs.release(tab, healthy) }()
+	start := time.Now()
+
+	// 4. Timeout (45s). Listeners registered on ctx go away with it, so they don't
+	// pile up on the tab.
+	ctx, cancel := context.WithTimeout(tab.ctx, 45*time.Second)
+	defer cancel()
+
+	headers := network.Headers{
+		"Accept-Language": "en-US,en;q=0.9",
+
+		// USE THE MATCHING HINT HERE:
+		"Sec-Ch-Ua": profile.ClientHint,
+
+		"Sec-Ch-Ua-Mobile":          "?0",
+		"Sec-Ch-Ua-Platform":        `"Linux"`, // Always Linux for Docker
+		"Sec-Fetch-Dest":            "document",
+		"Sec-Fetch-Mode":            "navigate",
+		"Sec-Fetch-Site":            "none",
+		"Sec-Fetch-User":            "?1",
+		"Upgrade-Insecure-Requests": "1",
+	}
+	policy := p.domainManager.Policy(targetURL)
+	if policy != nil {
+		for name, value := range policy.Headers {
+			headers[name] = value
+		}
+		if u, err := url.Parse(targetURL); err == nil {
+			p.cookies.Preload(u, policy.Cookies)
+		}
+	}
+
+	var htmlContent string
+	var pageTitle string
+	var chromeCookies []*network.Cookie
+	var waitedFor string
+
+	waits := p.Waits
+	if policy != nil && len(policy.waits) > 0 {
+		waits = policy.waits
+	}
+	if len(waits) == 0 {
+		waits = defaultWaits
+	}
+	activity := newPageActivity()
+	chromedp.ListenTarget(ctx, activity.listen)
+	api := p.Capture.recorder()
+	listenAPI(ctx, api)
+
+	// Follow the main document's HTTP redirects: each hop is a new request whose
+	// RedirectResponse is the answer from the previous URL.
+	var hopsMu sync.Mutex
+	lastURL := targetURL
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		e, ok := ev.(*network.EventRequestWillBeSent)
+		if !ok || e.RedirectResponse == nil || e.Type != network.ResourceTypeDocument {
+			return
+		}
+		hopsMu.Lock()
+		defer hopsMu.Unlock()
+		if e.RedirectResponse.URL == lastURL {
+			hops = append(hops, models.Redirect{URL: lastURL, StatusCode: int(e.RedirectResponse.Status)})
+			lastURL = e.Request.URL
+		}
+	})
+
+	// Redirects are vetted before Chrome follows them.
+	guard := &navGuard{ctx: req.context(), p: p, origin: targetURL}
+	tab.setGuard(guard.check)
+	defer tab.setGuard(nil)
+
+	// 5. Run Tasks ((A) the stealth script is injected once, when the tab is opened)
+	tab.visit(targetURL)
+	var scrollScript page.ScriptIdentifier
+	err = chromedp.Run(ctx,
+		network.SetExtraHTTPHeaders(headers),
+
+		// (B) Per-domain cookies from the policy file, then the session so far
+		chromedp.ActionFunc(func(c context.Context) error {
+			if policy == nil || p.cookies != nil {
+				return nil
+			}
+			for name, value := range policy.Cookies {
+				if err := network.SetCookie(name, value).WithURL(targetURL).Do(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+
+		p.cookies.toChrome(targetURL),
+
+		chromedp.EmulateViewport(1920, 1080),
+		navigate(targetURL),
+		activity.waitFor(waits, &waitedFor),
+
+		// 1. Move to a RANDOM point in the "Safe Zone"
+		// We target a box between X:300-500 and Y:300-500
+		chromedp.ActionFunc(func(c context.Context) error {
+			// Randomize X and Y by adding a random number between 0-200
+			x := 300 + rand.Intn(200)
+			y := 300 + rand.Intn(200)
+
+			// Move the mouse to this random spot
+			return chromedp.MouseClickXY(float64(x), float64(y)).Do(c)
+		}),
+
+		// 2. Scroll randomly (Reading behavior)
+		chromedp.ActionFunc(func(c context.Context) error {
+			scrollDistance := 300 + rand.Intn(400) // Scroll between 300px and 700px
+			script := fmt.Sprintf("window.scrollTo({top: %d, behavior: 'smooth'});", scrollDistance)
+			var err error
+			scrollScript, err = page.AddScriptToEvaluateOnNewDocument(script).Do(c)
+			return err
+		}),
+
+		chromedp.Evaluate(`document.title`, &pageTitle),
+
+		// (C) Run the Link Shim
+		chromedp.Evaluate(scriptLinkShim, nil),
+
+		// (D) Capture HTML, cut in the page so an oversized DOM never reaches Go whole
+		chromedp.Evaluate(scriptOuterHTML(p.Limits.MaxDecoded), &htmlContent),
+		chromedp.Location(&finalURL),
+		p.cookies.fromChrome(&finalURL, &chromeCookies),
+		api.collect(),
+		// The tab is reused, so don't leave the scroll script behind for the next page.
+		chromedp.ActionFunc(func(c context.Context) error {
+			return page.RemoveScriptToEvaluateOnNewDocument(scrollScript).Do(c)
+		}),
+	)
+	tab.visit(finalURL)
+	if refused := guard.err(); refused != nil {
+		// Our own refusal says nothing about the tab, the proxy or the host.
+		healthy = true
+		return nil, refused
+	}
+
+	switch {
+	case err != nil && isChromeProxyFailure(err):
+		p.Proxies.Report(proxy, false)
+	case err == nil:
+		p.Proxies.Report(proxy, true)
+	}
+
+	// Render time says little about server health, so Chrome only reports success/failure.
+	if err != nil {
+		p.domainManager.RecordResult(targetURL, 0, 0, nil)
+		return nil, err
+	}
+	p.domainManager.RecordResult(targetURL, 200, 0, nil)
+	healthy = true
+	p.cookies.storeChrome(finalURL, chromeCookies)
+
+	hopsMu.Lock()
+	defer hopsMu.Unlock()
+	if finalURL != lastURL && finalURL != targetURL {
+		// Redirected client-side after the last HTTP hop; the status is unknown.
+		hops = append(hops, models.Redirect{URL: lastURL})
+	}
+
+	log.Printf("[Chrome] Rendered %s (waited for %s): %q", targetURL, waitedFor, pageTitle)
+
+	return &FetchResponse{
+		Body:         io.NopCloser(strings.NewReader(htmlContent)),
+		StatusCode:   200,
+		FinalURL:     finalURL,
+		Redirects:    hops,
+		Duration:     time.Since(start),
+		WaitedFor:    waitedFor,
+		APIResponses: api.responses(),
+		APICaptured:  api != nil,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// CompliancePolicy controls which page-level robots directives are enforced.
+type CompliancePolicy struct {
+	HonorCanonical    bool // Store pages under their <link rel="canonical"> URL, if it is on the same site
+	HonorNoIndex      bool // Don't store pages marked noindex
+	HonorNoFollow     bool // Don't queue any links from pages marked nofollow
+	HonorLinkNofollow bool // Don't queue individual rel="nofollow" links
+}
+
+// Apply enforces the policy on a parsed page. It returns the links that may be
+// followed and reports whether the page may be stored.
+func (c CompliancePolicy) Apply(data *models.PageData) (links []string, store bool) {
+	store = true
+	if data.NoIndex && c.HonorNoIndex {
+		log.Printf("[Compliance] %s is noindex, not storing", data.URL)
+		store = false
+	}
+
+	if !(data.NoFollow && c.HonorNoFollow) {
+		links = append(links, data.OutboundLinks...)
+		if !c.HonorLinkNofollow {
+			links = append(links, data.NofollowLinks...)
+		}
+	}
+
+	if c.HonorCanonical && data.CanonicalURL != "" && data.CanonicalURL != data.URL {
+		if sameSite(data.URL, data.CanonicalURL) {
+			data.FetchedURL, data.URL = data.FinalURL(), data.CanonicalURL
+		} else {
+			log.Printf("[Compliance] Ignoring canonical %s of %s: another site", data.CanonicalURL, data.URL)
+		}
+	}
+
+	return links, store
+}
+
+// sameSite reports whether canonical is an http(s) URL on the page's host or
+// registrable domain. A page must not be able to store itself under another
+// site's URL and overwrite that site's copy.
+func sameSite(pageURL, canonical string) bool {
+	c, err := url.Parse(canonical)
+	if err != nil || (c.Scheme != "http" && c.Scheme != "https") {
+		return false
+	}
+	p, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	pageHost, canonicalHost := strings.ToLower(p.Hostname()), strings.ToLower(c.Hostname())
+	return pageHost == canonicalHost || registrableDomain(pageHost) == registrableDomain(canonicalHost)
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// SkipError is returned by Parser.Parse when a URL is deliberately not fetched
+// (or abandoned mid-fetch) because it isn't something we want to parse.
+type SkipError struct {
+	URL    string
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return fmt.Sprintf("skipped %s: %s", e.URL, e.Reason)
+}
+
+// ContentPolicy decides which targets are worth downloading and HTML-parsing.
+type ContentPolicy struct {
+	SkipExtensions   map[string]bool // Never fetch these extensions (without the dot)
+	AllowExtensions  map[string]bool // If set, only fetch these extensions (extension-less paths always pass)
+	HeadProbe        bool            // Send a HEAD first to check Content-Type / Content-Length
+	AllowedTypes     map[string]bool // Media types we parse, e.g. "text/html"
+	MaxContentLength int64           // Reject declared Content-Length above this (0 = unlimited)
+}
+
+func NewContentPolicy(skipExt, allowExt, allowedTypes []string, headProbe bool, maxContentLength int64) ContentPolicy {
+	toSet := func(items []string) map[string]bool {
+		set := make(map[string]bool, len(items))
+		for _, item := range items {
+			item = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(item), "."))
+			if item != "" {
+				set[item] = true
+			}
+		}
+		return set
+	}
+	return ContentPolicy{
+		SkipExtensions:   toSet(skipExt),
+		AllowExtensions:  toSet(allowExt),
+		HeadProbe:        headProbe,
+		AllowedTypes:     toSet(allowedTypes),
+		MaxContentLength: maxContentLength,
+	}
+}
+
+// CheckURL returns a non-empty reason if the URL's extension rules it out.
+func (c ContentPolicy) CheckURL(targetURL string) string {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return ""
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+	if ext == "" {
+		return ""
+	}
+	if c.SkipExtensions[ext] {
+		return fmt.Sprintf("extension .%s is skipped", ext)
+	}
+	if len(c.AllowExtensions) > 0 && !c.AllowExtensions[ext] {
+		return fmt.Sprintf("extension .%s is not allowed", ext)
+	}
+	return ""
+}
+
+// CheckHeader returns a non-empty reason if the response headers show the body
+// isn't HTML, or is larger than we accept. A missing Content-Type passes; the
+// caller can sniff the body instead.
+func (c ContentPolicy) CheckHeader(header http.Header) string {
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		if reason := c.checkType(contentType); reason != "" {
+			return reason
+		}
+	}
+	if c.MaxContentLength > 0 {
+		if n, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && n > c.MaxContentLength {
+			return fmt.Sprintf("content length %d exceeds %d", n, c.MaxContentLength)
+		}
+	}
+	return ""
+}
+
+// CheckSniffed checks the first bytes of a body that arrived without a Content-Type.
+func (c ContentPolicy) CheckSniffed(head []byte) string {
+	return c.checkType(http.DetectContentType(head))
+}
+
+func (c ContentPolicy) checkType(contentType string) string {
+	if len(c.AllowedTypes) == 0 {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	}
+	if !c.AllowedTypes[strings.ToLower(mediaType)] {
+		return fmt.Sprintf("content type %s is not HTML", mediaType)
+	}
+	return ""
+}
@@ -0,0 +1,329 @@
+package crawler
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"go-crawler/internal"
+	"go-crawler/pkg/models"
+	"golang.org/x/net/publicsuffix"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CookieStore persists cookies per site (registrable domain, e.g. "example.co.uk").
+type CookieStore interface {
+	LoadCookies(site string) ([]models.StoredCookie, error)
+	SaveCookies(site string, cookies []models.StoredCookie) error
+}
+
+// CookieJar is the http.CookieJar shared by static fetches and Chrome renders.
+// Each site gets its own jar, loaded from the store on first use and saved
+// back by Flush or when the site is evicted from memory.
+type CookieJar struct {
+	store CookieStore
+	mu    sync.Mutex // Serialises loading a site, so it only happens once
+	sites *internal.LRU[string, *siteJar]
+}
+
+type siteJar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	cookies map[cookieKey]models.StoredCookie // Everything set, for persistence (cookiejar can't list them)
+	dirty   bool
+	loaded  bool // The store's cookies are in; until then saving would overwrite them
+}
+
+type cookieKey struct {
+	host, name, domain, path string
+}
+
+// NewCookieJar creates a jar keeping at most maxSites sites in memory (0 = unbounded).
+// store may be nil, in which case cookies only live as long as the process.
+func NewCookieJar(store CookieStore, maxSites int, idleTTL time.Duration) *CookieJar {
+	j := &CookieJar{store: store}
+	j.sites = internal.NewLRU(maxSites, idleTTL, func(site string, s *siteJar) {
+		go j.save(site, s)
+	})
+	return j
+}
+
+// Preload sets the policy cookies for u's host, unless the site has already set
+// its own value for them. Once in the jar they are sent, updated and persisted like
+// any other cookie.
+func (j *CookieJar) Preload(u *url.URL, cookies map[string]string) {
+	if j == nil || len(cookies) == 0 {
+		return
+	}
+	have := make(map[string]bool)
+	for _, c := range j.Cookies(u) {
+		have[c.Name] = true
+	}
+	var missing []*http.Cookie
+	for name, value := range cookies {
+		if !have[name] {
+			missing = append(missing, &http.Cookie{Name: name, Value: value, Path: "/"})
+		}
+	}
+	if len(missing) > 0 {
+		j.SetCookies(u, missing)
+	}
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	site, s := j.siteFor(u.Hostname())
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	for _, c := range cookies {
+		key := cookieKey{host: u.Hostname(), name: c.Name, domain: strings.TrimPrefix(strings.ToLower(c.Domain), "."), path: c.Path}
+		if !strings.HasPrefix(key.path, "/") {
+			key.path = defaultCookiePath(u.Path)
+		}
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if c.MaxAge < 0 || (!expires.IsZero() && expires.Before(now)) {
+			if _, ok := s.cookies[key]; ok {
+				delete(s.cookies, key)
+				s.dirty = true
+			}
+			continue
+		}
+		stored := models.StoredCookie{
+			Site: site, Host: key.host, Name: c.Name, Value: c.Value, Domain: key.domain, Path: key.path,
+			Expires: expires, Secure: c.Secure, HttpOnly: c.HttpOnly,
+		}
+		if s.cookies[key] != stored {
+			s.cookies[key] = stored
+			s.dirty = true
+		}
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	_, s := j.siteFor(u.Hostname())
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jar.Cookies(u)
+}
+
+// Flush saves every site whose cookies changed since the last save.
+func (j *CookieJar) Flush() {
+	if j == nil {
+		return
+	}
+	type pending struct {
+		site string
+		jar  *siteJar
+	}
+	var sites []pending
+	j.sites.Range(func(site string, s *siteJar) {
+		sites = append(sites, pending{site, s})
+	})
+	for _, p := range sites {
+		j.save(p.site, p.jar)
+	}
+}
+
+func (j *CookieJar) save(site string, s *siteJar) {
+	if j.store == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.dirty || !s.loaded {
+		s.mu.Unlock()
+		return
+	}
+	cookies := make([]models.StoredCookie, 0, len(s.cookies))
+	for _, c := range s.cookies {
+		cookies = append(cookies, c)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err := j.store.SaveCookies(site, cookies); err != nil {
+		log.Printf("Failed to save cookies for %s: %v", site, err)
+	}
+}
+
+// defaultCookiePath is the path a cookie without one is scoped to (RFC 6265 5.1.4),
+// matching what cookiejar does with it.
+func defaultCookiePath(urlPath string) string {
+	i := strings.LastIndex(urlPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return urlPath[:i]
+}
+
+// siteFor returns the jar for the host's site, loading it from the store if needed.
+// A site the store failed to load is retried on its next use and never saved
+// before then.
+func (j *CookieJar) siteFor(host string) (string, *siteJar) {
+	site, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		site = host // IPs, localhost...
+	}
+	if s, ok := j.sites.Get(site); ok && s.isLoaded() {
+		return site, s
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s, ok := j.sites.Get(site)
+	if !ok {
+		jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return site, nil
+		}
+		s = &siteJar{jar: jar, cookies: make(map[cookieKey]models.StoredCookie), loaded: j.store == nil}
+		j.sites.Put(site, s)
+	}
+	if !s.isLoaded() {
+		j.load(site, s)
+	}
+	return site, s
+}
+
+func (s *siteJar) isLoaded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loaded
+}
+
+// load adds the store's cookies for site to s. Cookies set since s was created
+// are newer and win.
+func (j *CookieJar) load(site string, s *siteJar) {
+	stored, err := j.store.LoadCookies(site)
+	if err != nil {
+		log.Printf("Failed to load cookies for %s: %v", site, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, c := range stored {
+		key := cookieKey{host: c.Host, name: c.Name, domain: c.Domain, path: c.Path}
+		if _, ok := s.cookies[key]; ok || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+			continue
+		}
+		u := &url.URL{Scheme: "https", Host: c.Host, Path: "/"}
+		s.jar.SetCookies(u, []*http.Cookie{{
+			Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, Expires: c.Expires, Secure: c.Secure, HttpOnly: c.HttpOnly,
+		}})
+		s.cookies[key] = c
+	}
+	s.loaded = true
+}
+
+// chromeCookies are the jar's cookies for u's host with the attributes they were
+// set with, for Chrome to match against the pages it loads itself.
+func (j *CookieJar) chromeCookies(u *url.URL) []*network.SetCookieParams {
+	host := u.Hostname()
+	_, s := j.siteFor(host)
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var params []*network.SetCookieParams
+	now := time.Now()
+	for _, c := range s.cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			continue
+		}
+		set := network.SetCookie(c.Name, c.Value).WithPath(c.Path).WithSecure(c.Secure).WithHTTPOnly(c.HttpOnly)
+		switch {
+		case c.Domain == "" && c.Host == host:
+			// Host-only: set through the URL, without a domain
+			set = set.WithURL(u.Scheme + "://" + c.Host + c.Path)
+		case c.Domain != "" && (host == c.Domain || strings.HasSuffix(host, "."+c.Domain)):
+			set = set.WithDomain("." + c.Domain)
+		default:
+			continue
+		}
+		if !c.Expires.IsZero() {
+			expires := cdp.TimeSinceEpoch(c.Expires)
+			set = set.WithExpires(&expires)
+		}
+		params = append(params, set)
+	}
+	return params
+}
+
+// toChrome copies the jar's cookies for targetURL into the Chrome context before
+// it navigates, so a render continues the session static fetches started.
+func (j *CookieJar) toChrome(targetURL string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		u, err := url.Parse(targetURL)
+		if j == nil || err != nil {
+			return nil
+		}
+		for _, set := range j.chromeCookies(u) {
+			if err := set.Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// fromChrome reads back the cookies Chrome holds for finalURL after a render.
+func (j *CookieJar) fromChrome(finalURL *string, cookies *[]*network.Cookie) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if j == nil {
+			return nil
+		}
+		var err error
+		*cookies, err = network.GetCookies().WithUrls([]string{*finalURL}).Do(ctx)
+		return err
+	})
+}
+
+// storeChrome saves cookies read by fromChrome into the jar.
+func (j *CookieJar) storeChrome(finalURL string, cookies []*network.Cookie) {
+	u, err := url.Parse(finalURL)
+	if j == nil || err != nil || len(cookies) == 0 {
+		return
+	}
+	// Skipping unchanged values keeps the domain cookies we injected from being
+	// stored again under this host.
+	have := make(map[string]string)
+	for _, c := range j.Cookies(u) {
+		have[c.Name] = c.Value
+	}
+	converted := make([]*http.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		if value, ok := have[c.Name]; ok && value == c.Value {
+			continue
+		}
+		hc := &http.Cookie{Name: c.Name, Value: c.Value, Path: c.Path, Secure: c.Secure, HttpOnly: c.HTTPOnly}
+		// Chrome marks domain cookies with a leading dot; the others are host-only.
+		if strings.HasPrefix(c.Domain, ".") {
+			hc.Domain = c.Domain
+		}
+		if !c.Session {
+			hc.Expires = time.Unix(int64(c.Expires), 0)
+		}
+		converted = append(converted, hc)
+	}
+	j.SetCookies(u, converted)
+}
@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"errors"
+	"github.com/chromedp/cdproto/network"
+	"go-crawler/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryCookieStore struct {
+	mu      sync.Mutex
+	sites   map[string][]models.StoredCookie
+	loadErr error
+	saves   int
+}
+
+func (m *memoryCookieStore) LoadCookies(site string) ([]models.StoredCookie, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sites[site], m.loadErr
+}
+
+func (m *memoryCookieStore) SaveCookies(site string, cookies []models.StoredCookie) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sites[site] = cookies
+	m.saves++
+	return nil
+}
+
+func TestCookieJar_SessionSurvivesRestart(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("Cookie"))
+		mu.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", MaxAge: 3600})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	store := &memoryCookieStore{sites: map[string][]models.StoredCookie{}}
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	p.SetCookieJar(NewCookieJar(store, 0, 0))
+	for range 2 {
+		body, _, _, err := p.FetchStatic(server.URL + "/page")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+	}
+	if received[0] != "" || received[1] != "session=abc" {
+		t.Fatalf("Expected the cookie from the first response on the second request, got %q", received)
+	}
+
+	p.cookies.Flush()
+	restarted := NewCookieJar(store, 0, 0)
+	u, _ := url.Parse(server.URL + "/other")
+	if got := restarted.Cookies(u); len(got) != 1 || got[0].Value != "abc" {
+		t.Errorf("Expected the session to be reloaded from the store, got %v", got)
+	}
+
+	restarted.SetCookies(u, []*http.Cookie{{Name: "session", Path: "/", MaxAge: -1}})
+	restarted.Flush()
+	if stored, _ := store.LoadCookies("127.0.0.1"); len(stored) != 0 {
+		t.Errorf("Expected a deleted cookie to be removed from the store, got %+v", stored)
+	}
+}
+
+func TestCookieJar_PreloadKeepsSiteValues(t *testing.T) {
+	jar := NewCookieJar(nil, 0, 0)
+	u, _ := url.Parse("https://www.example.com/")
+
+	jar.Preload(u, map[string]string{"consent": "yes"})
+	jar.SetCookies(u, []*http.Cookie{{Name: "consent", Value: "all", Path: "/"}})
+	jar.Preload(u, map[string]string{"consent": "yes"})
+
+	if got := jar.Cookies(u); len(got) != 1 || got[0].Value != "all" {
+		t.Errorf("Expected the site's value to win over the preloaded one, got %v", got)
+	}
+}
+
+func TestCookieJar_StoreChrome(t *testing.T) {
+	jar := NewCookieJar(nil, 0, 0)
+	jar.storeChrome("https://www.example.com/page", []*network.Cookie{
+		{Name: "shared", Value: "1", Domain: ".example.com", Path: "/", Session: true},
+		{Name: "local", Value: "2", Domain: "www.example.com", Path: "/", Expires: float64(time.Now().Add(time.Hour).Unix())},
+	})
+
+	sub, _ := url.Parse("https://shop.example.com/")
+	if got := jar.Cookies(sub); len(got) != 1 || got[0].Name != "shared" {
+		t.Errorf("Expected only the domain cookie on a sibling host, got %v", got)
+	}
+	www, _ := url.Parse("https://www.example.com/")
+	if got := jar.Cookies(www); len(got) != 2 {
+		t.Errorf("Expected both cookies on the host that set them, got %v", got)
+	}
+}
+
+func TestCookieJar_FailedLoadIsNotSavedOver(t *testing.T) {
+	kept := models.StoredCookie{Site: "example.com", Host: "www.example.com", Name: "session", Value: "old", Path: "/"}
+	store := &memoryCookieStore{sites: map[string][]models.StoredCookie{"example.com": {kept}}, loadErr: errors.New("db down")}
+	jar := NewCookieJar(store, 0, 0)
+
+	u, _ := url.Parse("https://www.example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "fresh", Value: "1"}})
+	jar.Flush()
+	if store.saves != 0 {
+		t.Fatal("A site that failed to load must not be saved over its stored cookies")
+	}
+
+	store.loadErr = nil
+	if got := jar.Cookies(u); len(got) != 2 {
+		t.Errorf("Expected the load to be retried and merged, got %v", got)
+	}
+	jar.Flush()
+	if stored := store.sites["example.com"]; store.saves != 1 || len(stored) != 2 {
+		t.Errorf("Expected both cookies saved once loaded, got %d saves of %v", store.saves, stored)
+	}
+}
+
+func TestCookieJar_ChromeCookiesKeepAttributes(t *testing.T) {
+	jar := NewCookieJar(nil, 0, 0)
+	u, _ := url.Parse("https://www.example.com/shop/item")
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "shared", Value: "1", Domain: "example.com", Path: "/", Secure: true, HttpOnly: true, Expires: expires},
+		{Name: "cart", Value: "2", Path: "/shop"},
+	})
+
+	got := make(map[string]*network.SetCookieParams)
+	for _, c := range jar.chromeCookies(u) {
+		got[c.Name] = c
+	}
+	shared, cart := got["shared"], got["cart"]
+	if shared == nil || shared.Domain != ".example.com" || shared.Path != "/" || !shared.Secure || !shared.HTTPOnly ||
+		shared.Expires == nil || !shared.Expires.Time().Equal(expires) {
+		t.Errorf("Domain cookie lost its attributes: %+v", shared)
+	}
+	if cart == nil || cart.Domain != "" || cart.URL != "https://www.example.com/shop" || cart.Path != "/shop" || cart.Expires != nil {
+		t.Errorf("Expected a host-only session cookie on /shop, got %+v", cart)
+	}
+
+	sibling, _ := url.Parse("https://blog.example.com/")
+	if got := jar.chromeCookies(sibling); len(got) != 1 || got[0].Name != "shared" {
+		t.Errorf("Expected only the domain cookie on a sibling host, got %v", got)
+	}
+}
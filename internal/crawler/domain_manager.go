@@ -1,10 +1,14 @@
 package crawler
 
 import (
+	"context"
 	"github.com/temoto/robotstxt"
+	"go-crawler/internal"
 	"go-crawler/pkg/models"
-	"golang.org/x/net/context"
+	"golang.org/x/sync/singleflight"
 	"golang.org/x/time/rate"
+	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -14,113 +18,295 @@ import (
 
 type DomainManager struct {
 	mu           sync.RWMutex
-	limiters     map[string]*rate.Limiter
-	robotsCache  map[string]*robotstxt.Group
-	dynamicRules map[string]bool
-	fireDelay    time.Duration
+	throttles    *internal.LRU[string, *hostThrottle]
+	robotsCache  *internal.LRU[string, *robotsEntry]
+	dynamicRules *internal.LRU[string, *dynamicRule] // nil value = known not to need Chrome
+	dynamic      DynamicConfig
+	breaker      BreakerConfig
+	pageCounts   map[*DomainPolicy]int // Pages allowed per max_pages policy, so bounded by the policy file
+	hostStore    HostStateStore
+	pendingMu    sync.Mutex
+	pendingHosts map[string]models.HostState   // Evicted, still being written to hostStore
+	pendingRules map[string]models.DynamicHost // Evicted, still being written to the dynamic store
+	slots        *internal.LRU[string, chan struct{}]
+	groups       *internal.LRU[string, *rate.Limiter] // Politeness groups (shared IP/subnet), see RateConfig.Grouping
+	grouper      *hostGrouper
+
+	policies     *PolicySet
+	rates        RateConfig
+	robots       RobotsConfig
+	robotsClient *http.Client
+	robotsFlight singleflight.Group
+}
+
+// HostStateConfig bounds the per-host state kept in memory. Hosts not touched for
+// IdleTTL, or the least recently used ones beyond MaxHosts, are forgotten; their
+// learned pace and circuit breaker are written to Store first.
+type HostStateConfig struct {
+	MaxHosts int            // Per map (0 = unbounded)
+	IdleTTL  time.Duration  // 0 = never expire
+	Store    HostStateStore // Optional
 }
 
-func NewDomainManager(duration time.Duration) *DomainManager {
-	return &DomainManager{
-		limiters:     make(map[string]*rate.Limiter),
-		robotsCache:  make(map[string]*robotstxt.Group),
-		dynamicRules: make(map[string]bool),
-		fireDelay:    duration,
+// NewDomainManager creates a manager that paces requests to each host as described
+// by 'rates', obeys robots.txt as described by 'robots', and keeps per-host state
+// within the bounds of 'state'.
+func NewDomainManager(rates RateConfig, robots RobotsConfig, state HostStateConfig) *DomainManager {
+	robots = robots.withDefaults()
+	rates = rates.withDefaults()
+	d := &DomainManager{
+		dynamic:      DynamicConfig{}.withDefaults(),
+		pageCounts:   make(map[*DomainPolicy]int),
+		hostStore:    state.Store,
+		pendingHosts: make(map[string]models.HostState),
+		pendingRules: make(map[string]models.DynamicHost),
+		grouper:      newHostGrouper(rates.Grouping, rates.DNSCacheTTL, state.MaxHosts),
+		rates:        rates,
+		robots:       robots,
+		robotsClient: newRobotsClient(robots),
 	}
+
+	d.robotsCache = internal.NewLRU[string, *robotsEntry](state.MaxHosts, state.IdleTTL, nil)
+	d.throttles = internal.NewLRU(state.MaxHosts, state.IdleTTL, func(host string, t *hostThrottle) {
+		d.saveEvictedThrottle(host, t)
+		// The Crawl-delay floor lives on the throttle, so make sure it is
+		// re-applied from a fresh robots.txt if the host comes back.
+		d.robotsCache.Delete("http://" + host)
+		d.robotsCache.Delete("https://" + host)
+	})
+	d.dynamicRules = internal.NewLRU(state.MaxHosts, state.IdleTTL, d.saveEvictedRule)
+	d.slots = internal.NewLRU[string, chan struct{}](state.MaxHosts, state.IdleTTL, nil)
+	d.groups = internal.NewLRU[string, *rate.Limiter](state.MaxHosts, state.IdleTTL, nil)
+	return d
 }
 
-func (d *DomainManager) Wait(targetURL string) error {
-	u, err := url.Parse(targetURL)
+// SetPolicies installs per-domain overrides. Call it before crawling starts.
+func (d *DomainManager) SetPolicies(policies *PolicySet) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policies = policies
+}
+
+// Policy returns the per-domain policy for the link's host, or nil.
+func (d *DomainManager) Policy(link string) *DomainPolicy {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.policies.Lookup(link)
+}
+
+// AllowPage counts a page against its policy's max_pages and reports whether it may
+// be crawled. The budget is shared by every host the policy matches.
+func (d *DomainManager) AllowPage(link string) bool {
+	u, err := url.Parse(link)
 	if err != nil {
-		return err
+		return false
 	}
-	domain := u.Host
 
 	d.mu.Lock()
-	// Check if we already have a limiter for this domain
-	limiter, exists := d.limiters[domain]
-	if !exists {
-		// Create a new limiter: 1 request every 2 seconds
-		// rate.Every(2 * time.Second) = interval
-		// 1 = burst size (allow 1 request immediately, then wait)
-		limiter = rate.NewLimiter(rate.Every(d.fireDelay), 1)
-		d.limiters[domain] = limiter
+	defer d.mu.Unlock()
+	policy := d.policies.lookupHost(u.Hostname())
+	if policy == nil || policy.MaxPages <= 0 {
+		return true
 	}
-	d.mu.Unlock()
-
-	// This blocks the calling goroutine until the limiter allows it to proceed
-	return limiter.Wait(context.Background())
+	if d.pageCounts[policy] >= policy.MaxPages {
+		return false
+	}
+	d.pageCounts[policy]++
+	return true
 }
 
-func (d *DomainManager) IsAllowed(link string) bool {
+// Acquire blocks until the host has a free request slot (max_concurrency from its
+// policy, else RateConfig.MaxInFlight) and returns the function that gives it back.
+func (d *DomainManager) Acquire(ctx context.Context, link string) (release func(), err error) {
 	u, err := url.Parse(link)
 	if err != nil {
-		return false
+		return func() {}, nil
 	}
-	host := u.Host
 
 	d.mu.RLock()
-	group, exists := d.robotsCache[host]
+	slots := d.slots.GetOrCreate(u.Host, func() chan struct{} {
+		limit := d.rates.MaxInFlight
+		if policy := d.policies.lookupHost(u.Hostname()); policy != nil && policy.MaxConcurrency > 0 {
+			limit = policy.MaxConcurrency
+		}
+		if limit <= 0 {
+			return nil
+		}
+		return make(chan struct{}, limit)
+	})
 	d.mu.RUnlock()
 
-	if exists {
-		if group == nil {
-			return true
-		}
-		return group.Test(u.Path)
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	resp, err := http.Get(u.Scheme + "://" + host + "/robots.txt")
+// RobotsAgentToken derives the robots.txt product token from a User-Agent string,
+// e.g. "MyPageCrawler/1.0 (+https://example.com/bot)" -> "MyPageCrawler".
+func RobotsAgentToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+	token, _, _ = strings.Cut(token, "/")
+	return token
+}
 
-	var newGroup *robotstxt.Group
-	// Only parse if the request actually succeeded
-	if err == nil && resp.StatusCode == 200 {
-		data, err := robotstxt.FromResponse(resp)
-		if err == nil {
-			newGroup = data.FindGroup("MyGoCrawler")
-		}
-		resp.Body.Close()
+// Wait blocks until the host's rate limit (and its politeness group's) lets the
+// next request through, or ctx is done.
+func (d *DomainManager) Wait(ctx context.Context, targetURL string) error {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return err
 	}
+	domain := u.Host
 
 	d.mu.Lock()
-	defer d.mu.Unlock()
+	throttle := d.throttleFor(domain)
+	limiter, pausedUntil := throttle.limiter, throttle.pausedUntil
+	d.mu.Unlock()
 
-	if cachedGroup, alreadyFilled := d.robotsCache[host]; alreadyFilled {
-		if cachedGroup == nil {
-			return true
+	// Honour a Retry-After the host sent us before taking a token.
+	if pause := time.Until(pausedUntil); pause > 0 {
+		timer := time.NewTimer(pause)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
 		}
-		return cachedGroup.Test(u.Path)
 	}
 
-	d.robotsCache[host] = newGroup
+	// This blocks the calling goroutine until the limiter allows it to proceed
+	if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	// Then wait for the shared IP/subnet group, if grouping is on.
+	if group := d.groupLimiter(u.Hostname()); group != nil {
+		return group.Wait(ctx)
+	}
+	return nil
+}
 
-	if newGroup == nil {
-		return true
+// groupLimiter returns the limiter shared by every host in the same politeness
+// group, or nil when grouping is off. The group runs at GROUP_RATE_LIMIT unless a
+// policy of one of its hosts sets group_rate_limit; when hosts of one group ask
+// for different paces, the slowest wins.
+func (d *DomainManager) groupLimiter(hostname string) *rate.Limiter {
+	if d.rates.Grouping == GroupByHost {
+		return nil
+	}
+	delay := d.rates.GroupDelay
+	d.mu.RLock()
+	if policy := d.policies.lookupHost(hostname); policy != nil && policy.groupRateLimit > 0 {
+		delay = policy.groupRateLimit
+	}
+	d.mu.RUnlock()
+
+	key := d.grouper.key(hostname)
+	limiter := d.groups.GetOrCreate(key, func() *rate.Limiter {
+		return rate.NewLimiter(rate.Every(delay), 1)
+	})
+	if limit := rate.Every(delay); limit < limiter.Limit() {
+		limiter.SetLimit(limit)
+	}
+	return limiter
+}
+
+// RecordResult feeds a fetch outcome back into the host's rate and circuit breaker.
+// statusCode 0 means the request failed before a response arrived.
+func (d *DomainManager) RecordResult(targetURL string, statusCode int, latency time.Duration, header http.Header) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return
+	}
+
+	var retryAfter time.Duration
+	if header != nil {
+		retryAfter = parseRetryAfter(header)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	throttle := d.throttleFor(u.Host)
+	before := throttle.delay
+	throttle.record(d.rates, statusCode, latency, retryAfter)
+	if throttle.delay > before {
+		log.Printf("[Throttle] %s answered %d, slowing to 1 request every %s", u.Host, statusCode, throttle.delay)
 	}
-	return newGroup.Test(u.Path)
+	d.recordBreaker(u.Host, throttle, statusCode)
 }
 
-func (d *DomainManager) NeedsDynamic(targetURl string) bool {
-	u, _ := url.Parse(targetURl)
-	host := u.Host
+// HostRate is the current pacing of one host, for observability.
+type HostRate struct {
+	Host        string
+	Delay       time.Duration
+	PausedUntil time.Time
+	CircuitOpen bool
+}
 
+// Rates returns the effective rate of every host we've talked to.
+func (d *DomainManager) Rates() []HostRate {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return d.dynamicRules[host]
+
+	rates := make([]HostRate, 0, d.throttles.Len())
+	d.throttles.Range(func(host string, t *hostThrottle) {
+		rates = append(rates, HostRate{
+			Host:        host,
+			Delay:       t.delay,
+			PausedUntil: t.pausedUntil,
+			CircuitOpen: t.breaker.state != breakerClosed,
+		})
+	})
+	return rates
 }
 
-func (d *DomainManager) MarkDynamic(targetURL string) {
-	u, _ := url.Parse(targetURL)
-	host := u.Host
+// BaseDelay is the configured starting delay; hosts above it have been slowed down.
+func (d *DomainManager) BaseDelay() time.Duration {
+	return d.rates.Delay
+}
 
-	d.mu.Lock()
-	// Optimization: If we already know, don't hit DB
-	if d.dynamicRules[host] {
-		d.mu.Unlock()
+// throttleFor returns the host's throttle, creating it if needed. A new throttle
+// starts from the host's saved state, if it has any. Callers must hold d.mu.
+func (d *DomainManager) throttleFor(host string) *hostThrottle {
+	return d.throttles.GetOrCreate(host, func() *hostThrottle {
+		cfg := d.rates
+		hostname, _, err := net.SplitHostPort(host)
+		if err != nil {
+			hostname = host
+		}
+		// A per-domain rate_limit is both the starting pace and the fastest allowed.
+		if policy := d.policies.lookupHost(hostname); policy != nil && policy.rateLimit > 0 {
+			cfg.Delay, cfg.MinDelay = policy.rateLimit, policy.rateLimit
+		}
+		t := newHostThrottle(cfg)
+		if h, found := d.savedHostState(host); found {
+			t.restore(d.rates, h)
+		}
+		return t
+	})
+}
+
+// applyCrawlDelay slows the host down to the robots.txt Crawl-delay, but only
+// when it is stricter than the configured rate. Callers must hold d.mu.
+func (d *DomainManager) applyCrawlDelay(host string, group *robotstxt.Group) {
+	if group == nil || group.CrawlDelay <= d.rates.MinDelay {
 		return
 	}
-	d.dynamicRules[host] = true
-	d.mu.Unlock()
+	d.throttleFor(host).setFloor(group.CrawlDelay)
+}
+
+func (d *DomainManager) IsAllowed(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return d.robotsFor(u).allows(u.RequestURI())
 }
 
 func getDomain(url string) models.DataSource {
@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"github.com/temoto/robotstxt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDomainManager_AcquireLimitsInFlight(t *testing.T) {
+	d := NewDomainManager(RateConfig{MaxInFlight: 2}, RobotsConfig{}, HostStateConfig{})
+	d.SetPolicies(&PolicySet{Policies: []*DomainPolicy{{Hosts: []string{"slow.com"}, MaxConcurrency: 1}}})
+
+	measure := func(link string) int32 {
+		var inFlight, peak atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				release, err := d.Acquire(context.Background(), link)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer release()
+				n := inFlight.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				inFlight.Add(-1)
+			}()
+		}
+		wg.Wait()
+		return peak.Load()
+	}
+
+	if peak := measure("https://fast.com/a"); peak != 2 {
+		t.Errorf("Expected default limit of 2 in flight, got %d", peak)
+	}
+	if peak := measure("https://slow.com/a"); peak != 1 {
+		t.Errorf("Expected policy override of 1 in flight, got %d", peak)
+	}
+
+	// A cancelled context gives up instead of waiting for a slot.
+	hold, _ := d.Acquire(context.Background(), "https://slow.com/b")
+	defer hold()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.Acquire(ctx, "https://slow.com/c"); err == nil {
+		t.Error("Expected an error when the context is cancelled")
+	}
+}
+
+func TestRobotsAgentToken(t *testing.T) {
+	tests := []struct {
+		userAgent, want string
+	}{
+		{"MyPageCrawler/1.0 (+https://example.com/bot)", "MyPageCrawler"},
+		{"MyPageCrawler/1.0", "MyPageCrawler"},
+		{"  MyPageCrawler  ", "MyPageCrawler"},
+		{"Mozilla/5.0 (compatible; ExampleBot/2.1)", "Mozilla"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := RobotsAgentToken(tt.userAgent); got != tt.want {
+			t.Errorf("RobotsAgentToken(%q) = %q, expected %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestDomainManager_ApplyCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		group     *robotstxt.Group
+		wantDelay time.Duration
+		wantFloor time.Duration
+	}{
+		{"no group", nil, time.Second, time.Second},
+		{"no crawl-delay", &robotstxt.Group{}, time.Second, time.Second},
+		{"looser than our rate", &robotstxt.Group{CrawlDelay: 500 * time.Millisecond}, time.Second, time.Second},
+		{"stricter than our rate", &robotstxt.Group{CrawlDelay: 5 * time.Second}, 5 * time.Second, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		d := NewDomainManager(RateConfig{Delay: time.Second}, RobotsConfig{}, HostStateConfig{})
+		d.mu.Lock()
+		d.applyCrawlDelay("example.com", tt.group)
+		throttle := d.throttleFor("example.com")
+		d.mu.Unlock()
+		if throttle.delay != tt.wantDelay || throttle.minDelay != tt.wantFloor {
+			t.Errorf("%s: delay %s (floor %s), expected %s (floor %s)", tt.name, throttle.delay, throttle.minDelay, tt.wantDelay, tt.wantFloor)
+		}
+	}
+
+	// The floor survives AIMD speed-ups.
+	d := NewDomainManager(RateConfig{Delay: time.Second}, RobotsConfig{}, HostStateConfig{})
+	d.mu.Lock()
+	d.applyCrawlDelay("example.com", &robotstxt.Group{CrawlDelay: 5 * time.Second})
+	throttle := d.throttleFor("example.com")
+	for i := 0; i < 100; i++ {
+		throttle.record(d.rates, 200, time.Millisecond, 0)
+	}
+	d.mu.Unlock()
+	if throttle.delay < 5*time.Second {
+		t.Errorf("Healthy responses sped the host up past its Crawl-delay: %s", throttle.delay)
+	}
+}
+
+func TestDomainManager_WaitStopsWithContext(t *testing.T) {
+	d := NewDomainManager(RateConfig{Delay: 100 * time.Millisecond}, RobotsConfig{}, HostStateConfig{})
+	d.RecordResult("https://busy.com/", http.StatusTooManyRequests, 0, http.Header{"Retry-After": {"3600"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := d.Wait(ctx, "https://busy.com/page"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait ignored the context for %s", elapsed)
+	}
+}
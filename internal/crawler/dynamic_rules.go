@@ -0,0 +1,207 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"log"
+	"net/url"
+	"time"
+)
+
+// DynamicConfig controls how long a host stays "needs Chrome" once marked.
+type DynamicConfig struct {
+	TTL             time.Duration // After this, static fetches are tried again
+	StaticSuccesses int           // Good static fetches (after expiry) needed to forget the host
+	Store           DynamicStore  // Optional: keeps the decision across restarts
+}
+
+// DynamicStore persists hosts that need dynamic rendering.
+type DynamicStore interface {
+	LoadDynamicHosts() ([]models.DynamicHost, error)
+	LoadDynamicHost(host string) (h models.DynamicHost, found bool, err error)
+	SaveDynamicHost(host models.DynamicHost) error
+	DeleteDynamicHost(host string) error
+}
+
+func (c DynamicConfig) withDefaults() DynamicConfig {
+	if c.TTL <= 0 {
+		c.TTL = 7 * 24 * time.Hour
+	}
+	if c.StaticSuccesses <= 0 {
+		c.StaticSuccesses = 3
+	}
+	return c
+}
+
+// dynamicRule is what we know about one host that needed Chrome.
+type dynamicRule struct {
+	markedAt   time.Time
+	expiresAt  time.Time
+	hits       int // How many times the host has been marked; repeat offenders stay dynamic longer
+	staticWins int // Good static fetches since the rule expired
+	saved      bool
+}
+
+// SetDynamicConfig installs the expiry policy and loads previously learned hosts
+// from the store. Call it before crawling starts.
+func (d *DomainManager) SetDynamicConfig(cfg DynamicConfig) error {
+	cfg = cfg.withDefaults()
+
+	var hosts []models.DynamicHost
+	if cfg.Store != nil {
+		var err error
+		if hosts, err = cfg.Store.LoadDynamicHosts(); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dynamic = cfg
+	for _, h := range hosts {
+		d.dynamicRules.Put(h.Host, &dynamicRule{markedAt: h.MarkedAt, expiresAt: h.ExpiresAt, hits: h.Hits, saved: true})
+	}
+	if len(hosts) > 0 {
+		log.Printf("Loaded %d dynamic-rendering hosts", len(hosts))
+	}
+	return nil
+}
+
+// NeedsDynamic reports whether the host is known to need Chrome. Once a rule
+// expires it returns false, so the next fetches try static again.
+func (d *DomainManager) NeedsDynamic(targetURl string) bool {
+	u, _ := url.Parse(targetURl)
+	host := u.Host
+
+	d.loadDynamicRule(host)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	rule, _ := d.dynamicRules.Get(host)
+	return rule != nil && time.Now().Before(rule.expiresAt)
+}
+
+func (d *DomainManager) MarkDynamic(targetURL string) {
+	u, _ := url.Parse(targetURL)
+	host := u.Host
+
+	d.loadDynamicRule(host)
+	d.mu.Lock()
+	rule, _ := d.dynamicRules.Get(host)
+	// Optimization: If we already know, don't hit DB
+	if rule != nil && time.Now().Before(rule.expiresAt) {
+		d.mu.Unlock()
+		return
+	}
+	if rule == nil {
+		rule = &dynamicRule{}
+		d.dynamicRules.Put(host, rule)
+	}
+	rule.hits++
+	rule.staticWins = 0
+	rule.markedAt = time.Now()
+	// Hosts that keep going back to needing JS stay dynamic longer (up to 8x TTL).
+	rule.expiresAt = rule.markedAt.Add(d.dynamic.TTL * time.Duration(min(rule.hits, 8)))
+	rule.saved = false
+	record := models.DynamicHost{Host: host, MarkedAt: rule.markedAt, ExpiresAt: rule.expiresAt, Hits: rule.hits}
+	store := d.dynamic.Store
+	d.mu.Unlock()
+
+	if store != nil {
+		if err := store.SaveDynamicHost(record); err != nil {
+			log.Printf("Failed to save dynamic host %s: %v", host, err)
+			return
+		}
+		d.mu.Lock()
+		rule.saved = true
+		d.mu.Unlock()
+	}
+}
+
+// RecordStatic notes a static fetch that was good enough to use. After enough of
+// them on an expired rule, the host goes back to static-first for good.
+func (d *DomainManager) RecordStatic(targetURL string) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return
+	}
+	host := u.Host
+
+	d.mu.Lock()
+	rule, _ := d.dynamicRules.Get(host)
+	if rule == nil || time.Now().Before(rule.expiresAt) {
+		d.mu.Unlock()
+		return
+	}
+	rule.staticWins++
+	if rule.staticWins < d.dynamic.StaticSuccesses {
+		d.mu.Unlock()
+		return
+	}
+	d.dynamicRules.Put(host, nil)
+	store := d.dynamic.Store
+	d.mu.Unlock()
+
+	log.Printf("[SmartParse] %s is serving usable static pages again, no longer dynamic", host)
+	if store != nil {
+		if err := store.DeleteDynamicHost(host); err != nil {
+			log.Printf("Failed to delete dynamic host %s: %v", host, err)
+		}
+	}
+}
+
+// loadDynamicRule fills the cache for a host that isn't in it (never seen, or
+// evicted) from the store. Hosts the store doesn't know are cached as nil so
+// we only ask once.
+func (d *DomainManager) loadDynamicRule(host string) {
+	d.mu.RLock()
+	_, cached := d.dynamicRules.Get(host)
+	store := d.dynamic.Store
+	d.mu.RUnlock()
+	if cached {
+		return
+	}
+
+	var rule *dynamicRule
+	d.pendingMu.Lock()
+	h, pending := d.pendingRules[host]
+	d.pendingMu.Unlock()
+	if pending {
+		rule = &dynamicRule{markedAt: h.MarkedAt, expiresAt: h.ExpiresAt, hits: h.Hits}
+	} else if store != nil {
+		h, found, err := store.LoadDynamicHost(host)
+		if err != nil {
+			// Don't cache anything, so the next fetch asks again.
+			log.Printf("Failed to load dynamic host %s: %v", host, err)
+			return
+		}
+		if found {
+			rule = &dynamicRule{markedAt: h.MarkedAt, expiresAt: h.ExpiresAt, hits: h.Hits, saved: true}
+		}
+	}
+	d.mu.Lock()
+	d.dynamicRules.GetOrCreate(host, func() *dynamicRule { return rule })
+	d.mu.Unlock()
+}
+
+// saveEvictedRule writes a rule that is being dropped from memory to the store,
+// unless it is already there. It runs with d.mu held by whoever caused the
+// eviction, so the write is done in the background; until it lands the rule is
+// served from pendingRules.
+func (d *DomainManager) saveEvictedRule(host string, rule *dynamicRule) {
+	if rule == nil || rule.saved || d.dynamic.Store == nil {
+		return
+	}
+	record := models.DynamicHost{Host: host, MarkedAt: rule.markedAt, ExpiresAt: rule.expiresAt, Hits: rule.hits}
+	d.pendingMu.Lock()
+	d.pendingRules[host] = record
+	d.pendingMu.Unlock()
+	go func() {
+		if err := d.dynamic.Store.SaveDynamicHost(record); err != nil {
+			log.Printf("Failed to save evicted dynamic host %s: %v", host, err)
+		}
+		d.pendingMu.Lock()
+		defer d.pendingMu.Unlock()
+		if d.pendingRules[host] == record {
+			delete(d.pendingRules, host)
+		}
+	}()
+}
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"testing"
+	"time"
+)
+
+type memoryDynamicStore struct {
+	hosts map[string]models.DynamicHost
+}
+
+func (m *memoryDynamicStore) LoadDynamicHosts() ([]models.DynamicHost, error) {
+	var hosts []models.DynamicHost
+	for _, h := range m.hosts {
+		hosts = append(hosts, h)
+	}
+	return hosts, nil
+}
+
+func (m *memoryDynamicStore) LoadDynamicHost(host string) (models.DynamicHost, bool, error) {
+	h, found := m.hosts[host]
+	return h, found, nil
+}
+
+func (m *memoryDynamicStore) SaveDynamicHost(h models.DynamicHost) error {
+	m.hosts[h.Host] = h
+	return nil
+}
+
+func (m *memoryDynamicStore) DeleteDynamicHost(host string) error {
+	delete(m.hosts, host)
+	return nil
+}
+
+func TestDomainManager_DynamicRulesPersistAndDecay(t *testing.T) {
+	store := &memoryDynamicStore{hosts: map[string]models.DynamicHost{
+		"loaded.com": {Host: "loaded.com", MarkedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), Hits: 1},
+	}}
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{}, HostStateConfig{})
+	if err := d.SetDynamicConfig(DynamicConfig{TTL: time.Hour, StaticSuccesses: 2, Store: store}); err != nil {
+		t.Fatal(err)
+	}
+	if !d.NeedsDynamic("https://loaded.com/page") {
+		t.Error("Expected hosts from the store to be dynamic at startup")
+	}
+
+	d.MarkDynamic("https://spa.com/")
+	if _, saved := store.hosts["spa.com"]; !saved || !d.NeedsDynamic("https://spa.com/other") {
+		t.Fatal("Expected MarkDynamic to take effect and be persisted")
+	}
+
+	// Static successes don't count while the rule is still active.
+	d.RecordStatic("https://spa.com/")
+	d.RecordStatic("https://spa.com/")
+	if !d.NeedsDynamic("https://spa.com/") {
+		t.Error("Active rule should not decay")
+	}
+
+	// Expire it: static is tried again and enough good fetches forget the host.
+	d.mu.Lock()
+	rule, _ := d.dynamicRules.Get("spa.com")
+	rule.expiresAt = time.Now().Add(-time.Second)
+	d.mu.Unlock()
+	if d.NeedsDynamic("https://spa.com/") {
+		t.Error("Expired rule should let static fetches through")
+	}
+	d.RecordStatic("https://spa.com/")
+	d.RecordStatic("https://spa.com/")
+	if _, saved := store.hosts["spa.com"]; saved {
+		t.Error("Expected the host to be deleted from the store after static recovered")
+	}
+}
+
+func TestDomainManager_EvictedDynamicRulesReload(t *testing.T) {
+	store := &memoryDynamicStore{hosts: map[string]models.DynamicHost{}}
+	d := NewDomainManager(RateConfig{}, RobotsConfig{}, HostStateConfig{MaxHosts: 2})
+	if err := d.SetDynamicConfig(DynamicConfig{TTL: time.Hour, Store: store}); err != nil {
+		t.Fatal(err)
+	}
+
+	d.MarkDynamic("https://spa.com/")
+	// Push spa.com out of memory.
+	d.NeedsDynamic("https://a.com/")
+	d.NeedsDynamic("https://b.com/")
+	if _, cached := d.dynamicRules.Get("spa.com"); cached {
+		t.Fatal("Expected spa.com to be evicted")
+	}
+	if !d.NeedsDynamic("https://spa.com/page") {
+		t.Error("Expected an evicted rule to be reloaded from the store")
+	}
+}
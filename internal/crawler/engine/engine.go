@@ -6,6 +6,7 @@ import (
 	"go-crawler/internal"
 	"go-crawler/internal/crawler"
 	"log"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,7 +15,7 @@ import (
 // Processor defines how to crawl a single page.
 // It returns extracted data items (T) and new links to follow.
 type Processor[T any] interface {
-	Process(url string) (data []T, links []string, err error)
+	Process(ctx context.Context, url string) (data []T, links []string, err error)
 }
 
 // Sink defines how to persist the data.
@@ -22,12 +23,27 @@ type Sink[T any] interface {
 	Save(batch []T) error
 }
 
+// Redirected is implemented by data items that know the URL their fetch ended up
+// at. The engine marks it visited, so pages reached through redirects (A->B, C->B)
+// or linked directly later are not fetched again.
+type Redirected interface {
+	FinalURL() string
+}
+
 // Config holds worker settings.
 type Config struct {
 	Workers   int
 	BatchSize int
 	RateLimit time.Duration
-	MaxURLs   int // 0 = unlimited
+	MaxURLs   int                        // 0 = unlimited
+	Traps     *crawler.TrapDetector      // Optional: drops trap URLs before they are queued
+	Sitemaps  *crawler.SitemapDiscoverer // Optional: seeds the worklist from each new host's sitemaps
+	// SitemapWorkers bounds how many hosts' sitemaps are read at once, and so the
+	// entries held in memory (0 = 2). Hosts beyond the queue skip their sitemaps.
+	SitemapWorkers int
+	// DeferOpenCircuits re-queues URLs of a host whose circuit breaker is open once
+	// it may be retried; otherwise they are dropped.
+	DeferOpenCircuits bool
 }
 
 // Engine orchestrates the crawling process.
@@ -38,11 +54,17 @@ type Engine[T any] struct {
 
 	// State
 	visited   *internal.SafeMap
+	hostsSeen *internal.SafeMap
 	domainMgr *crawler.DomainManager
 	worklist  chan []string
 	results   chan T
 	waitGroup sync.WaitGroup
 	urlCount  atomic.Int64
+
+	deferredMu sync.Mutex
+	deferred   map[string][]string // Host -> links waiting for its circuit to half-open
+
+	sitemapHosts chan string // New hosts waiting for a sitemap worker
 }
 
 func NewEngine[T any](cfg Config, proc Processor[T], sink Sink[T], domainMgr *crawler.DomainManager) *Engine[T] {
@@ -51,9 +73,13 @@ func NewEngine[T any](cfg Config, proc Processor[T], sink Sink[T], domainMgr *cr
 		processor: proc,
 		sink:      sink,
 		visited:   internal.NewSafeMap(),
+		hostsSeen: internal.NewSafeMap(),
 		domainMgr: domainMgr,
 		worklist:  make(chan []string, 1000),
 		results:   make(chan T, cfg.BatchSize*20),
+		deferred:  make(map[string][]string),
+
+		sitemapHosts: make(chan string, 1000),
 	}
 }
 
@@ -69,10 +95,19 @@ func (engine *Engine[T]) Run(ctx context.Context, startURLs ...string) {
 		go engine.startCrawlWorker(ctx, i)
 	}
 
-	// 3. Seed the worklist
-	go func() {
-		engine.worklist <- startURLs
-	}()
+	// 3. Start Sitemap Workers (not waited for: they may be blocked on a full worklist)
+	if engine.config.Sitemaps != nil {
+		workers := engine.config.SitemapWorkers
+		if workers <= 0 {
+			workers = 2
+		}
+		for range workers {
+			go engine.startSitemapWorker(ctx)
+		}
+	}
+
+	// 4. Seed the worklist
+	go engine.enqueue(ctx, startURLs)
 
 	fmt.Printf("Engine started with %d workers\n", engine.config.Workers)
 	engine.waitGroup.Wait()
@@ -87,41 +122,171 @@ func (engine *Engine[T]) startCrawlWorker(ctx context.Context, id int) {
 			return
 		case list := <-engine.worklist:
 			for _, link := range list {
-				// Checks & Rate Limiting handled by the Engine, not the Processor
-				if engine.visited.Contains(link) || !engine.domainMgr.IsAllowed(link) {
-					continue
-				}
-				if engine.config.MaxURLs > 0 && engine.urlCount.Load() >= int64(engine.config.MaxURLs) {
-					log.Printf("Reached MAX_URLS limit (%d), stopping workers", engine.config.MaxURLs)
+				if !engine.crawl(ctx, link) {
 					return
 				}
-				engine.urlCount.Add(1)
-				err := engine.domainMgr.Wait(link)
-				if err != nil {
-					log.Println(err)
-				}
+			}
+		}
+	}
+}
 
-	
-				// Execute the Strategy
-				data, outbound, err := engine.processor.Process(link)
-				if err != nil {
-						continue
-				}
+// crawl fetches one link from the worklist, if it passes the checks, and queues
+// what it found. It returns false when the worker should stop.
+func (engine *Engine[T]) crawl(ctx context.Context, link string) bool {
+	// Checks & Rate Limiting handled by the Engine, not the Processor.
+	// Duplicates go first, so they are dropped rather than deferred.
+	if engine.visited.Seen(link) || !engine.domainMgr.IsAllowed(link) {
+		return true
+	}
+	// Not yet marked visited, so a deferred URL is not mistaken for a duplicate later.
+	// From here on the link may be the host's probe: every path must release it.
+	releaseProbe, retryIn, open := engine.domainMgr.CircuitOpen(link)
+	if open {
+		engine.deferLink(ctx, link, retryIn)
+		return true
+	}
+	defer releaseProbe()
 
-				// Send results to storage
-				for _, item := range data {
-					engine.results <- item
-				}
+	if engine.visited.Contains(link) || !engine.domainMgr.AllowPage(link) {
+		return true
+	}
+	if engine.config.MaxURLs > 0 && engine.urlCount.Load() >= int64(engine.config.MaxURLs) {
+		log.Printf("Reached MAX_URLS limit (%d), stopping workers", engine.config.MaxURLs)
+		return false
+	}
+	engine.urlCount.Add(1)
+	engine.discoverSitemaps(link)
+	// Per-host in-flight limit, on top of the rate limiter
+	release, err := engine.domainMgr.Acquire(ctx, link)
+	if err != nil {
+		return false
+	}
+	if err := engine.domainMgr.Wait(ctx, link); err != nil {
+		release()
+		if ctx.Err() != nil {
+			return false
+		}
+		log.Println(err)
+		return true
+	}
+
+	// Execute the Strategy
+	data, outbound, err := engine.processor.Process(ctx, link)
+	release()
+	if err != nil {
+		return true
+	}
+
+	// Send results to storage
+	for _, item := range data {
+		if r, ok := any(item).(Redirected); ok && r.FinalURL() != link {
+			engine.visited.Contains(r.FinalURL())
+		}
+		engine.results <- item
+	}
 
-				// Queue new links
-				// (Non-blocking send optimization could go here)
-				//log.Printf("[Worker %d] Found %d Valid Links, adding to work Queue\n", id, len(outbound))
-				go func(l []string) { engine.worklist <- l }(outbound)
+	// Queue new links
+	if engine.config.Traps != nil {
+		outbound = engine.config.Traps.Filter(outbound)
+	}
+	go engine.enqueue(ctx, outbound)
+	return true
+}
+
+// deferLink puts a link of a host with an open circuit back on the worklist once
+// the host may be retried, or drops it if deferring is off. Each host has one
+// queue and one timer; its links go back together, the first becoming the probe.
+func (engine *Engine[T]) deferLink(ctx context.Context, link string, retryIn time.Duration) {
+	if !engine.config.DeferOpenCircuits {
+		log.Printf("[Breaker] Dropping %s: host circuit is open", link)
+		return
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return
+	}
+
+	engine.deferredMu.Lock()
+	defer engine.deferredMu.Unlock()
+	links, waiting := engine.deferred[u.Host]
+	engine.deferred[u.Host] = append(links, link)
+	if waiting {
+		return
+	}
+	time.AfterFunc(retryIn, func() {
+		engine.deferredMu.Lock()
+		links := engine.deferred[u.Host]
+		delete(engine.deferred, u.Host)
+		engine.deferredMu.Unlock()
+		engine.enqueue(ctx, links)
+	})
+}
+
+// enqueue hands links to the workers, giving up if the crawl is stopped first.
+func (engine *Engine[T]) enqueue(ctx context.Context, links []string) bool {
+	select {
+	case engine.worklist <- links:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// discoverSitemaps hands a host to the sitemap workers the first time it is crawled.
+func (engine *Engine[T]) discoverSitemaps(link string) {
+	if engine.config.Sitemaps == nil {
+		return
+	}
+	u, err := url.Parse(link)
+	if err != nil || engine.hostsSeen.Contains(u.Scheme+"://"+u.Host) {
+		return
+	}
+	select {
+	case engine.sitemapHosts <- link:
+	default:
+		log.Printf("[Sitemap] Too many hosts waiting, not reading the sitemaps of %s", u.Host)
+	}
+}
+
+func (engine *Engine[T]) startSitemapWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case link := <-engine.sitemapHosts:
+			if !engine.queueSitemap(ctx, link) {
+				return
 			}
 		}
 	}
 }
 
+// queueSitemap queues the sitemap URLs of link's host. It returns false once ctx is done.
+func (engine *Engine[T]) queueSitemap(ctx context.Context, link string) bool {
+	entries := engine.config.Sitemaps.Discover(ctx, link)
+	if len(entries) == 0 {
+		return true
+	}
+	log.Printf("[Sitemap] Queuing %d URLs for %s", len(entries), link)
+
+	// Entries arrive best-first; queue them in chunks so workers can start early.
+	const chunkSize = 500
+	for start := 0; start < len(entries); start += chunkSize {
+		end := min(start+chunkSize, len(entries))
+		links := make([]string, 0, end-start)
+		for _, e := range entries[start:end] {
+			links = append(links, e.URL)
+		}
+		if engine.config.Traps != nil {
+			links = engine.config.Traps.Filter(links)
+		}
+		if !engine.enqueue(ctx, links) {
+			return false
+		}
+	}
+	return true
+}
+
 func (engine *Engine[T]) startStorageWorker(ctx context.Context) {
 	defer engine.waitGroup.Done()
 	buffer := make([]T, 0, engine.config.BatchSize)
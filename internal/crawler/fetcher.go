@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"context"
+	"go-crawler/pkg/models"
+	"io"
+	"net/http"
+	"time"
+)
+
+// FetchRequest is one page to fetch. Header holds extra request headers, e.g.
+// the validators of a conditional GET; backends that can't send them ignore them.
+type FetchRequest struct {
+	URL    string
+	Header http.Header
+	Ctx    context.Context // Cancels the fetch and its waits on redirects (nil = background)
+}
+
+func (r FetchRequest) context() context.Context {
+	if r.Ctx == nil {
+		return context.Background()
+	}
+	return r.Ctx
+}
+
+// FetchResponse is what a Fetcher got back. Body is already decompressed and
+// must be closed by the caller; size limits are applied on top by the Parser.
+type FetchResponse struct {
+	Body         io.ReadCloser
+	StatusCode   int
+	Header       http.Header          // nil when the backend doesn't expose headers (Chrome)
+	FinalURL     string               // Where redirects ended up; the request URL if there were none
+	Redirects    []models.Redirect    // Hops taken to get to FinalURL, oldest first
+	Duration     time.Duration        // Time to the response (static) or the rendered DOM (Chrome)
+	WaitedFor    string               // Renders only: the wait condition that ended the wait, or WaitTimedOut
+	APIResponses []models.APIResponse // Renders only: XHR/fetch responses kept by the Parser's APICapture
+	APICaptured  bool                 // APICapture was on, so APIResponses is complete even when empty
+	Truncated    bool                 // The backend already cut Body at the size limit
+}
+
+// Fetcher is a page download backend. The Parser has one for plain HTTP and one
+// for rendering; decideAction picks between them based on what the first returned.
+type Fetcher interface {
+	Fetch(req FetchRequest) (*FetchResponse, error)
+}
+
+// FetcherFunc lets a plain function be used as a Fetcher.
+type FetcherFunc func(req FetchRequest) (*FetchResponse, error)
+
+func (f FetcherFunc) Fetch(req FetchRequest) (*FetchResponse, error) {
+	return f(req)
+}
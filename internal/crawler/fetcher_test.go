@@ -0,0 +1,144 @@
+package crawler
+
+import (
+	"errors"
+	"go-crawler/pkg/models"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFetcher answers every URL with the same page and counts the calls.
+type fakeFetcher struct {
+	body  string
+	calls int
+}
+
+func (f *fakeFetcher) Fetch(req FetchRequest) (*FetchResponse, error) {
+	f.calls++
+	return &FetchResponse{
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		FinalURL:   req.URL,
+	}, nil
+}
+
+func TestParser_FallsBackToDynamicFetcher(t *testing.T) {
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	static := &fakeFetcher{body: `<html><body><div id="root"></div></body></html>`}
+	dynamic := &fakeFetcher{body: `<html><head><title>Rendered</title></head><body><p>` + strings.Repeat("Content. ", 30) + `</p></body></html>`}
+	p.Static, p.Dynamic = static, dynamic
+
+	data, err := p.Parse("https://spa.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if static.calls != 1 || dynamic.calls != 1 || data.Title != "Rendered" {
+		t.Errorf("Expected an empty static page to be re-fetched dynamically, got %d/%d calls, title %q", static.calls, dynamic.calls, data.Title)
+	}
+}
+
+func TestReplayFetcher_RecordsThenReplays(t *testing.T) {
+	dir := t.TempDir()
+	live := FetcherFunc(func(req FetchRequest) (*FetchResponse, error) {
+		return &FetchResponse{
+			Body:        io.NopCloser(strings.NewReader("hello")),
+			StatusCode:  http.StatusOK,
+			FinalURL:    req.URL + "/final",
+			Redirects:   []models.Redirect{{URL: req.URL, StatusCode: http.StatusFound}},
+			APICaptured: true,
+		}, nil
+	})
+
+	recorder := &ReplayFetcher{Dir: dir, Next: live}
+	resp, err := recorder.Fetch(FetchRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != "hello" {
+		t.Errorf("Expected the live body to be passed through, got %q", body)
+	}
+
+	replayer := &ReplayFetcher{Dir: dir}
+	resp, err = replayer.Fetch(FetchRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "hello" || resp.FinalURL != "https://example.com/final" || len(resp.Redirects) != 1 || !resp.APICaptured {
+		t.Errorf("Unexpected replay: %q %s %v", body, resp.FinalURL, resp.Redirects)
+	}
+
+	if _, err := replayer.Fetch(FetchRequest{URL: "https://example.com/other"}); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded for an unknown URL, got %v", err)
+	}
+}
+
+func TestReplayFetcher_RecordsOversizedPagesLikeTheParser(t *testing.T) {
+	limits := BodyLimits{MaxRaw: 100, MaxDecoded: 1000, Truncate: true}
+	live := FetcherFunc(func(req FetchRequest) (*FetchResponse, error) {
+		// Like httpFetcher: the body fails with errRawLimit past MaxRaw.
+		body, _ := limits.decode(io.NopCloser(strings.NewReader(strings.Repeat("x", 500))), "")
+		return &FetchResponse{Body: body, StatusCode: http.StatusOK, FinalURL: req.URL}, nil
+	})
+	dir := t.TempDir()
+	recorder := &ReplayFetcher{Dir: dir, Next: live, Limits: limits}
+	resp, err := recorder.Fetch(FetchRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Expected an oversized page to be truncated, got %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); len(body) != 100 || !resp.Truncated {
+		t.Errorf("Expected the first MaxRaw bytes marked truncated, got %d bytes, truncated=%v", len(body), resp.Truncated)
+	}
+	if resp, err := (&ReplayFetcher{Dir: dir}).Fetch(FetchRequest{URL: "https://example.com"}); err != nil || !resp.Truncated {
+		t.Errorf("Expected the replay to report the cut too, got %v", err)
+	}
+
+	limits.Truncate = false
+	recorder = &ReplayFetcher{Dir: t.TempDir(), Next: live, Limits: limits}
+	var skipped *SkipError
+	if _, err := recorder.Fetch(FetchRequest{URL: "https://example.com"}); !errors.As(err, &skipped) {
+		t.Errorf("Expected a SkipError without TRUNCATE_OVERSIZED, got %v", err)
+	}
+}
+
+func TestParser_ErrorPagesDontDemoteDynamicHost(t *testing.T) {
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{})
+	if err := d.SetDynamicConfig(DynamicConfig{TTL: time.Hour, StaticSuccesses: 2}); err != nil {
+		t.Fatal(err)
+	}
+	d.MarkDynamic("https://spa.example/")
+	d.mu.Lock()
+	rule, _ := d.dynamicRules.Get("spa.example")
+	rule.expiresAt = time.Now().Add(-time.Second)
+	d.mu.Unlock()
+
+	status := http.StatusNotFound
+	p := NewParser("test", nil, d)
+	p.Static = FetcherFunc(func(req FetchRequest) (*FetchResponse, error) {
+		return &FetchResponse{
+			Body:       io.NopCloser(strings.NewReader(`<html><body><p>` + strings.Repeat("Not here. ", 30) + `</p></body></html>`)),
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			FinalURL:   req.URL,
+		}, nil
+	})
+
+	for range 3 {
+		p.Parse("https://spa.example/missing")
+	}
+	if rule, _ := d.dynamicRules.Get("spa.example"); rule == nil {
+		t.Fatal("Expected 404 pages not to count as static successes")
+	}
+
+	status = http.StatusOK
+	for range 2 {
+		p.Parse("https://spa.example/page")
+	}
+	if rule, _ := d.dynamicRules.Get("spa.example"); rule != nil {
+		t.Error("Expected good static pages to demote the host")
+	}
+}
@@ -3,6 +3,7 @@ package crawler
 import (
 	"fmt"
 	"go-crawler/pkg/models"
+	"golang.org/x/net/publicsuffix"
 	"net/url"
 	"strings"
 )
@@ -35,25 +36,79 @@ func (filter AlwaysFilter) Filter(source models.DataSource, link string) bool {
 	return true
 }
 
+// ScopeMode decides how far from its seed an InDomainFilter lets the crawl wander.
+type ScopeMode int
+
+const (
+	ScopeHost   ScopeMode = iota // Only the seed's exact host
+	ScopeDomain                  // The seed's registrable domain (eTLD+1) and all its subdomains
+	ScopePrefix                  // The seed's host, under the seed's URL path
+)
+
+func ParseScopeMode(s string) (ScopeMode, error) {
+	switch strings.ToLower(s) {
+	case "host":
+		return ScopeHost, nil
+	case "domain":
+		return ScopeDomain, nil
+	case "prefix":
+		return ScopePrefix, nil
+	default:
+		return 0, fmt.Errorf("unknown scope mode %q (use host, domain or prefix)", s)
+	}
+}
+
 type InDomainFilter struct {
-	Domain string
+	Mode       ScopeMode
+	Host       string // Used by ScopeHost and ScopePrefix
+	Domain     string // Used by ScopeDomain
+	PathPrefix string // Used by ScopePrefix
 }
 
-func NewInDomainFilter(startURL string) (*InDomainFilter, error) {
+func NewInDomainFilter(startURL string, mode ScopeMode) (*InDomainFilter, error) {
 	u, err := url.Parse(startURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid start URL: %w", err)
 	}
 
-	// Extract hostname and strip "www." to allow subdomains
-	host := u.Hostname()
-	domain := strings.TrimPrefix(host, "www.")
-
-	if domain == "" {
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
 		return nil, fmt.Errorf("could not extract domain from %s", startURL)
 	}
 
-	return &InDomainFilter{Domain: domain}, nil
+	domain := registrableDomain(host)
+
+	prefix := u.Path
+	if prefix == "" {
+		prefix = "/"
+	}
+
+	return &InDomainFilter{Mode: mode, Host: host, Domain: domain, PathPrefix: prefix}, nil
+}
+
+// registrableDomain uses the Public Suffix List so that "shop.example.co.uk" maps to
+// "example.co.uk", not "co.uk". IPs and single-label hosts have no registrable
+// domain; they are returned as-is.
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// NewSeedScopeFilter gives every seed its own scope and accepts a link if it
+// falls inside any of them.
+func NewSeedScopeFilter(mode ScopeMode, seeds ...string) (URLFilter, error) {
+	var scopes OrFilter
+	for _, seed := range seeds {
+		f, err := NewInDomainFilter(seed, mode)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, f)
+	}
+	return scopes, nil
 }
 
 func (filter InDomainFilter) Filter(source models.DataSource, link string) bool {
@@ -61,6 +116,18 @@ func (filter InDomainFilter) Filter(source models.DataSource, link string) bool
 	if err != nil {
 		return false
 	}
+	host := strings.ToLower(u.Hostname())
 
-	return strings.Contains(strings.ToLower(u.Host), strings.ToLower(filter.Domain))
+	switch filter.Mode {
+	case ScopeHost:
+		return host == filter.Host
+	case ScopePrefix:
+		if host != filter.Host {
+			return false
+		}
+		prefix := strings.TrimSuffix(filter.PathPrefix, "/")
+		return u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+	default:
+		return host == filter.Domain || strings.HasSuffix(host, "."+filter.Domain)
+	}
 }
@@ -0,0 +1,278 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-crawler/pkg/models"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// --- Combinators ---
+
+// AndFilter accepts a link only if every child filter accepts it.
+type AndFilter []URLFilter
+
+func (filters AndFilter) Filter(source models.DataSource, link string) bool {
+	for _, f := range filters {
+		if !f.Filter(source, link) {
+			return false
+		}
+	}
+	return true
+}
+
+// OrFilter accepts a link if any child filter accepts it.
+type OrFilter []URLFilter
+
+func (filters OrFilter) Filter(source models.DataSource, link string) bool {
+	for _, f := range filters {
+		if f.Filter(source, link) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotFilter inverts its child filter.
+type NotFilter struct {
+	Inner URLFilter
+}
+
+func (filter NotFilter) Filter(source models.DataSource, link string) bool {
+	return !filter.Inner.Filter(source, link)
+}
+
+// --- Rules ---
+
+// RegexFilter matches the full URL against a regular expression.
+type RegexFilter struct {
+	Pattern *regexp.Regexp
+}
+
+func (filter RegexFilter) Filter(source models.DataSource, link string) bool {
+	return filter.Pattern.MatchString(link)
+}
+
+// GlobFilter matches the URL path against a shell glob (e.g. "/products/*/reviews").
+type GlobFilter struct {
+	Pattern string
+}
+
+func (filter GlobFilter) Filter(source models.DataSource, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	matched, err := path.Match(filter.Pattern, u.Path)
+	return err == nil && matched
+}
+
+// PathPrefixFilter matches URLs whose path starts with Prefix.
+type PathPrefixFilter struct {
+	Prefix string
+}
+
+func (filter PathPrefixFilter) Filter(source models.DataSource, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(u.Path, filter.Prefix)
+}
+
+// HostSetFilter matches URLs whose host is exactly one of Hosts.
+type HostSetFilter struct {
+	Hosts map[string]bool
+}
+
+func NewHostSetFilter(hosts ...string) HostSetFilter {
+	set := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		set[strings.ToLower(h)] = true
+	}
+	return HostSetFilter{Hosts: set}
+}
+
+func (filter HostSetFilter) Filter(source models.DataSource, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return filter.Hosts[strings.ToLower(u.Hostname())]
+}
+
+// ExtensionFilter matches URLs whose path ends in one of Extensions (without the dot).
+type ExtensionFilter struct {
+	Extensions map[string]bool
+}
+
+func NewExtensionFilter(extensions ...string) ExtensionFilter {
+	set := make(map[string]bool, len(extensions))
+	for _, e := range extensions {
+		set[strings.ToLower(strings.TrimPrefix(e, "."))] = true
+	}
+	return ExtensionFilter{Extensions: set}
+}
+
+func (filter ExtensionFilter) Filter(source models.DataSource, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	ext := strings.TrimPrefix(path.Ext(u.Path), ".")
+	return ext != "" && filter.Extensions[strings.ToLower(ext)]
+}
+
+// QueryParamFilter matches URLs that carry the query parameter Param.
+type QueryParamFilter struct {
+	Param string
+}
+
+func (filter QueryParamFilter) Filter(source models.DataSource, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return u.Query().Has(filter.Param)
+}
+
+// --- Config ---
+
+// FilterSpec is the on-disk form of a filter. Exactly one field must be set, e.g.
+//
+//	and:
+//	  - hosts: [example.com, www.example.com]
+//	  - not: {extensions: [pdf, zip]}
+//	  - or:
+//	      - path_prefix: /blog/
+//	      - regex: '/p/\d+'
+type FilterSpec struct {
+	And        []FilterSpec `json:"and,omitempty" yaml:"and,omitempty"`
+	Or         []FilterSpec `json:"or,omitempty" yaml:"or,omitempty"`
+	Not        *FilterSpec  `json:"not,omitempty" yaml:"not,omitempty"`
+	Regex      string       `json:"regex,omitempty" yaml:"regex,omitempty"`
+	Glob       string       `json:"glob,omitempty" yaml:"glob,omitempty"`
+	PathPrefix string       `json:"path_prefix,omitempty" yaml:"path_prefix,omitempty"`
+	Hosts      []string     `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	Extensions []string     `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+	QueryParam string       `json:"query_param,omitempty" yaml:"query_param,omitempty"`
+	Always     bool         `json:"always,omitempty" yaml:"always,omitempty"`
+}
+
+// Build turns the spec into a URLFilter.
+func (spec FilterSpec) Build() (URLFilter, error) {
+	var built []URLFilter
+	add := func(f URLFilter) { built = append(built, f) }
+
+	if spec.And != nil {
+		children, err := buildAll(spec.And)
+		if err != nil {
+			return nil, fmt.Errorf("and: %w", err)
+		}
+		add(AndFilter(children))
+	}
+	if spec.Or != nil {
+		children, err := buildAll(spec.Or)
+		if err != nil {
+			return nil, fmt.Errorf("or: %w", err)
+		}
+		add(OrFilter(children))
+	}
+	if spec.Not != nil {
+		inner, err := spec.Not.Build()
+		if err != nil {
+			return nil, fmt.Errorf("not: %w", err)
+		}
+		add(NotFilter{Inner: inner})
+	}
+	if spec.Regex != "" {
+		re, err := regexp.Compile(spec.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("regex %q: %w", spec.Regex, err)
+		}
+		add(RegexFilter{Pattern: re})
+	}
+	if spec.Glob != "" {
+		if _, err := path.Match(spec.Glob, ""); err != nil {
+			return nil, fmt.Errorf("glob %q: %w", spec.Glob, err)
+		}
+		add(GlobFilter{Pattern: spec.Glob})
+	}
+	if spec.PathPrefix != "" {
+		add(PathPrefixFilter{Prefix: spec.PathPrefix})
+	}
+	if spec.Hosts != nil {
+		add(NewHostSetFilter(spec.Hosts...))
+	}
+	if spec.Extensions != nil {
+		add(NewExtensionFilter(spec.Extensions...))
+	}
+	if spec.QueryParam != "" {
+		add(QueryParamFilter{Param: spec.QueryParam})
+	}
+	if spec.Always {
+		add(AlwaysFilter{})
+	}
+
+	switch len(built) {
+	case 0:
+		return nil, errors.New("empty filter rule")
+	case 1:
+		return built[0], nil
+	default:
+		return nil, fmt.Errorf("a filter rule must set exactly one key, found %d", len(built))
+	}
+}
+
+func buildAll(specs []FilterSpec) ([]URLFilter, error) {
+	filters := make([]URLFilter, 0, len(specs))
+	for i, spec := range specs {
+		f, err := spec.Build()
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// LoadFilterFile reads a FilterSpec from a .json, .yaml or .yml file.
+func LoadFilterFile(filename string) (URLFilter, error) {
+	var spec FilterSpec
+	if err := decodeConfigFile(filename, &spec); err != nil {
+		return nil, err
+	}
+	filter, err := spec.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter in %s: %w", filename, err)
+	}
+	return filter, nil
+}
+
+// decodeConfigFile unmarshals a JSON or YAML file, chosen by extension.
+func decodeConfigFile(filename string, v any) error {
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		err = json.Unmarshal(raw, v)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, v)
+	default:
+		return fmt.Errorf("unsupported config format %q (use .json, .yaml or .yml)", filepath.Ext(filename))
+	}
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFilterFile(t *testing.T) {
+	yamlRules := `
+and:
+  - hosts: [example.com]
+  - not: {extensions: [pdf, ZIP]}
+  - or:
+      - path_prefix: /blog/
+      - glob: /shop/*/reviews
+      - regex: '/p/\d+$'
+      - query_param: page
+`
+	jsonRules := `{"and": [
+		{"hosts": ["example.com"]},
+		{"not": {"extensions": ["pdf", "ZIP"]}},
+		{"or": [
+			{"path_prefix": "/blog/"},
+			{"glob": "/shop/*/reviews"},
+			{"regex": "/p/\\d+$"},
+			{"query_param": "page"}
+		]}
+	]}`
+
+	tests := []struct {
+		link     string
+		expected bool
+	}{
+		{"https://example.com/blog/hello", true},
+		{"https://example.com/blog/report.pdf", false},
+		{"https://example.com/shop/keyboards/reviews", true},
+		{"https://example.com/shop/keyboards/specs", false},
+		{"https://example.com/p/1234", true},
+		{"https://example.com/archive.zip?page=2", false},
+		{"https://example.com/archive?page=2", true},
+		{"https://other.com/blog/hello", false},
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"rules.yaml": yamlRules, "rules.json": jsonRules} {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		filter, err := LoadFilterFile(filename)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		for _, tt := range tests {
+			if got := filter.Filter(models.None, tt.link); got != tt.expected {
+				t.Errorf("%s: Filter(%q) = %v, expected %v", name, tt.link, got, tt.expected)
+			}
+		}
+	}
+}
+
+func TestFilterSpec_BuildRejectsAmbiguousRules(t *testing.T) {
+	if _, err := (FilterSpec{Regex: "a", PathPrefix: "/b"}).Build(); err == nil {
+		t.Error("Expected an error for a rule with two keys")
+	}
+	if _, err := (FilterSpec{}).Build(); err == nil {
+		t.Error("Expected an error for an empty rule")
+	}
+	if _, err := (FilterSpec{Regex: "("}).Build(); err == nil {
+		t.Error("Expected an error for an invalid regex")
+	}
+}
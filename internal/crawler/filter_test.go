@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"testing"
+)
+
+func TestInDomainFilter_Scopes(t *testing.T) {
+	tests := []struct {
+		mode     ScopeMode
+		seed     string
+		link     string
+		expected bool
+	}{
+		{ScopeDomain, "https://www.example.com", "https://blog.example.com/post", true},
+		{ScopeDomain, "https://www.example.com", "https://notexample.com/", false},
+		{ScopeDomain, "https://www.example.com", "https://example.com.evil.io/", false},
+		{ScopeDomain, "https://shop.example.co.uk", "https://www.example.co.uk/", true},
+		{ScopeDomain, "https://shop.example.co.uk", "https://other.co.uk/", false},
+		{ScopeHost, "https://www.example.com", "https://www.example.com:443/a", true},
+		{ScopeHost, "https://www.example.com", "https://example.com/a", false},
+		{ScopePrefix, "https://example.com/docs/", "https://example.com/docs/intro", true},
+		{ScopePrefix, "https://example.com/docs", "https://example.com/docs", true},
+		{ScopePrefix, "https://example.com/docs", "https://example.com/docsearch", false},
+		{ScopePrefix, "https://example.com/docs", "https://cdn.example.com/docs/a", false},
+	}
+
+	for _, tt := range tests {
+		f, err := NewInDomainFilter(tt.seed, tt.mode)
+		if err != nil {
+			t.Fatalf("NewInDomainFilter(%q): %v", tt.seed, err)
+		}
+		if got := f.Filter(models.None, tt.link); got != tt.expected {
+			t.Errorf("mode %d seed %q: Filter(%q) = %v, expected %v", tt.mode, tt.seed, tt.link, got, tt.expected)
+		}
+	}
+}
+
+func TestNewSeedScopeFilter_PerSeed(t *testing.T) {
+	f, err := NewSeedScopeFilter(ScopePrefix, "https://a.com/blog/", "https://b.com/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Filter(models.None, "https://b.com/docs/x") {
+		t.Error("Expected link inside the second seed's scope to pass")
+	}
+	if f.Filter(models.None, "https://a.com/docs/x") {
+		t.Error("Scopes must not mix hosts and prefixes across seeds")
+	}
+}
@@ -1,34 +1,83 @@
 package crawler
 
 import (
+	"context"
+	"errors"
 	"go-crawler/pkg/models"
+	"log"
+	"time"
 )
 
 // PageProcessor implements engine.Processor for scraping full page content.
 type PageProcessor struct {
-	Parser *Parser
-	Filter URLFilter
+	Parser     *Parser
+	Filter     URLFilter
+	Compliance CompliancePolicy
+	Reporter   SuppressionReporter // Optional: records URLs the parser skipped
+	Links      LinkStore           // Optional: re-emits the stored links of pages that come back 304
 }
 
 // Process crawls a single page, extracting its text content and metadata.
-func (processor *PageProcessor) Process(url string) ([]models.PageData, []string, error) {
+func (processor *PageProcessor) Process(ctx context.Context, url string) ([]models.PageData, []string, error) {
 	// 1. Use your existing Parse method to get title, text, and links
-	data, err := processor.Parser.Parse(url)
+	data, err := processor.Parser.ParseContext(ctx, url)
 	if err != nil {
+		var skipped *SkipError
+		if errors.As(err, &skipped) && processor.Reporter != nil {
+			processor.Reporter.Report(models.Suppression{
+				URL:    skipped.URL,
+				Source: "content",
+				Reason: skipped.Reason,
+				At:     time.Now(),
+			})
+		}
 		return nil, nil, err
 	}
 
+	// Unchanged since the last crawl (304): just refresh it, and follow the links
+	// stored last time. This run's visited set starts empty, so they still matter.
+	if data.NotModified {
+		return []models.PageData{data}, processor.storedLinks(url), nil
+	}
+
+	// 2. Enforce robots directives (noindex, nofollow, canonical)
+	links, store := processor.Compliance.Apply(&data)
+
 	var validLinks []string
-	for _, link := range data.OutboundLinks {
+	for _, link := range links {
 		// We pass 'models.None' as the source because InDomainFilter ignores it anyway
 		if processor.Filter.Filter(models.None, link) {
 			validLinks = append(validLinks, link)
 		}
 	}
 
-	// 2. Wrap the single PageData result into a slice
-	results := []models.PageData{data}
+	// 3. Wrap the single PageData result into a slice. The links followed are what
+	// gets stored with it, to be re-emitted if the page comes back 304.
+	var results []models.PageData
+	if store {
+		data.OutboundLinks = validLinks
+		results = append(results, data)
+	}
 
-	// 3. Return the data and the links to follow
+	// 4. Return the data and the links to follow
 	return results, validLinks, nil
 }
+
+// storedLinks returns the links followed from url on its last crawl that still pass the filter.
+func (processor *PageProcessor) storedLinks(url string) []string {
+	if processor.Links == nil {
+		return nil
+	}
+	links, err := processor.Links.LoadLinks(url)
+	if err != nil {
+		log.Printf("Failed to load stored links of %s: %v", url, err)
+		return nil
+	}
+	var validLinks []string
+	for _, link := range links {
+		if processor.Filter.Filter(models.None, link) {
+			validLinks = append(validLinks, link)
+		}
+	}
+	return validLinks
+}
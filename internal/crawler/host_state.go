@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"log"
+	"time"
+)
+
+// HostStateStore persists the learned pace and circuit breaker of hosts whose
+// throttle is evicted from memory, so a host that comes back doesn't start over
+// at full speed with a closed circuit.
+type HostStateStore interface {
+	LoadHostState(host string) (h models.HostState, found bool, err error)
+	SaveHostState(h models.HostState) error
+}
+
+// worthSaving reports whether the throttle learned anything a fresh one wouldn't
+// start with. Restored throttles are always saved, so stale state is overwritten.
+func (t *hostThrottle) worthSaving(cfg RateConfig) bool {
+	return t.restored || t.delay != cfg.Delay || time.Now().Before(t.pausedUntil) ||
+		t.breaker.state != breakerClosed || t.breaker.failures > 0
+}
+
+func (t *hostThrottle) snapshot(host string) models.HostState {
+	h := models.HostState{
+		Host:        host,
+		Delay:       t.delay,
+		PausedUntil: t.pausedUntil,
+		Failures:    t.breaker.failures,
+		CoolOff:     t.breaker.coolOff,
+		OpenUntil:   t.breaker.openUntil,
+	}
+	switch t.breaker.state {
+	case breakerOpen:
+		h.CircuitOpen = true
+	case breakerHalfOpen:
+		// The probe's answer is lost with the throttle: let the next one through right away.
+		h.CircuitOpen, h.OpenUntil = true, time.Now()
+	}
+	return h
+}
+
+// restore applies saved state to a fresh throttle, within the current rate bounds.
+func (t *hostThrottle) restore(cfg RateConfig, h models.HostState) {
+	if h.Delay > 0 {
+		t.setDelay(min(max(h.Delay, t.minDelay), cfg.MaxDelay))
+	}
+	t.pausedUntil = h.PausedUntil
+	t.breaker.failures = h.Failures
+	t.breaker.coolOff = h.CoolOff
+	if h.CircuitOpen {
+		t.breaker.state, t.breaker.openUntil = breakerOpen, h.OpenUntil
+	}
+	t.restored = true
+}
+
+// saveEvictedThrottle writes a throttle that is being dropped from memory to the
+// store. It runs with d.mu held by whoever caused the eviction, so the write is
+// done in the background; until it lands the state is served from pendingHosts.
+func (d *DomainManager) saveEvictedThrottle(host string, t *hostThrottle) {
+	if d.hostStore == nil || !t.worthSaving(d.rates) {
+		return
+	}
+	record := t.snapshot(host)
+	d.pendingMu.Lock()
+	d.pendingHosts[host] = record
+	d.pendingMu.Unlock()
+	go func() {
+		if err := d.hostStore.SaveHostState(record); err != nil {
+			log.Printf("Failed to save evicted host state %s: %v", host, err)
+		}
+		d.pendingMu.Lock()
+		defer d.pendingMu.Unlock()
+		if d.pendingHosts[host] == record {
+			delete(d.pendingHosts, host)
+		}
+	}()
+}
+
+// savedHostState returns what was saved for a host whose throttle is being
+// created (never seen, or evicted).
+func (d *DomainManager) savedHostState(host string) (models.HostState, bool) {
+	d.pendingMu.Lock()
+	h, pending := d.pendingHosts[host]
+	d.pendingMu.Unlock()
+	if pending || d.hostStore == nil {
+		return h, pending
+	}
+
+	h, found, err := d.hostStore.LoadHostState(host)
+	if err != nil {
+		log.Printf("Failed to load host state %s: %v", host, err)
+		return h, false
+	}
+	return h, found
+}
@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryHostStore struct {
+	mu    sync.Mutex
+	hosts map[string]models.HostState
+	saved chan string
+}
+
+func (s *memoryHostStore) LoadHostState(host string) (models.HostState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.hosts[host]
+	return h, ok, nil
+}
+
+func (s *memoryHostStore) SaveHostState(h models.HostState) error {
+	s.mu.Lock()
+	s.hosts[h.Host] = h
+	s.mu.Unlock()
+	s.saved <- h.Host
+	return nil
+}
+
+func TestDomainManager_EvictedHostKeepsOpenCircuit(t *testing.T) {
+	store := &memoryHostStore{hosts: make(map[string]models.HostState), saved: make(chan string, 10)}
+	d := NewDomainManager(RateConfig{Delay: 100 * time.Millisecond}, RobotsConfig{}, HostStateConfig{MaxHosts: 1, Store: store})
+	d.SetBreakerConfig(BreakerConfig{Failures: 1, CoolOff: time.Hour})
+
+	d.RecordResult("https://down.com/", http.StatusServiceUnavailable, 0, nil)
+	if _, _, open := d.CircuitOpen("https://down.com/"); !open {
+		t.Fatal("Expected the circuit to open")
+	}
+
+	d.RecordResult("https://up.com/", http.StatusOK, 0, nil) // Evicts down.com
+	select {
+	case host := <-store.saved:
+		if host != "down.com" {
+			t.Fatalf("Expected down.com to be saved, got %s", host)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Evicted host state was not saved")
+	}
+
+	if _, retryIn, open := d.CircuitOpen("https://down.com/"); !open || retryIn < 59*time.Minute {
+		t.Errorf("Expected the circuit to still be open after eviction, got open=%v retryIn=%s", open, retryIn)
+	}
+	if h := store.hosts["down.com"]; h.Delay != time.Second {
+		t.Errorf("Expected the backed-off delay to be saved, got %s", h.Delay)
+	}
+}
+
+func TestDomainManager_RestoresHostStateWhenRobotsLoadsFirst(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nCrawl-delay: 1\n"))
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	store := &memoryHostStore{hosts: map[string]models.HostState{
+		host: {Host: host, Delay: 5 * time.Second, CircuitOpen: true, OpenUntil: time.Now().Add(time.Hour)},
+	}, saved: make(chan string, 10)}
+	d := NewDomainManager(RateConfig{Delay: 100 * time.Millisecond}, RobotsConfig{Agent: "test"}, HostStateConfig{Store: store})
+	d.SetBreakerConfig(BreakerConfig{Failures: 1, CoolOff: time.Hour})
+
+	// The Crawl-delay creates the throttle before CircuitOpen or Wait see the host.
+	if !d.IsAllowed(server.URL + "/") {
+		t.Fatal("Expected the page to be allowed")
+	}
+	if _, _, open := d.CircuitOpen(server.URL + "/"); !open {
+		t.Error("Expected the saved open circuit to be restored")
+	}
+	d.mu.Lock()
+	delay := d.throttleFor(host).delay
+	d.mu.Unlock()
+	if delay != 5*time.Second {
+		t.Errorf("Expected the saved delay to be restored, got %s", delay)
+	}
+}
+
+// blockingHostStore holds every save until release is closed.
+type blockingHostStore struct {
+	memoryHostStore
+	release chan struct{}
+}
+
+func (s *blockingHostStore) SaveHostState(h models.HostState) error {
+	<-s.release
+	return s.memoryHostStore.SaveHostState(h)
+}
+
+func TestDomainManager_ReturningHostSeesPendingSave(t *testing.T) {
+	store := &blockingHostStore{
+		memoryHostStore: memoryHostStore{hosts: make(map[string]models.HostState), saved: make(chan string, 10)},
+		release:         make(chan struct{}),
+	}
+	d := NewDomainManager(RateConfig{Delay: 100 * time.Millisecond}, RobotsConfig{}, HostStateConfig{MaxHosts: 1, Store: store})
+	d.SetBreakerConfig(BreakerConfig{Failures: 1, CoolOff: time.Hour})
+
+	d.RecordResult("https://down.com/", http.StatusServiceUnavailable, 0, nil)
+	d.RecordResult("https://up.com/", http.StatusOK, 0, nil) // Evicts down.com; its save is stuck
+
+	if _, _, open := d.CircuitOpen("https://down.com/"); !open {
+		t.Error("Expected the state still being saved to be restored")
+	}
+	close(store.release)
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// httpFetcher is the default static backend: a GET through the Parser's
+// http.Client, with its proxies, cookie jar and redirect policy.
+type httpFetcher struct {
+	p *Parser
+}
+
+func (f *httpFetcher) Fetch(fetchReq FetchRequest) (*FetchResponse, error) {
+	p, targetURL := f.p, fetchReq.URL
+	req, err := http.NewRequestWithContext(fetchReq.context(), "GET", targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", p.UserAgent)
+	// Decompression is ours (not the Transport's) so BodyLimits can see both sizes.
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	for name, values := range fetchReq.Header {
+		req.Header[name] = values
+	}
+	if policy := p.domainManager.Policy(targetURL); policy != nil {
+		for name, value := range policy.Headers {
+			req.Header.Set(name, value)
+		}
+		if p.cookies != nil {
+			p.cookies.Preload(req.URL, policy.Cookies)
+		} else {
+			for name, value := range policy.Cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+		}
+	}
+
+	req, proxy := p.proxied(req)
+	start := time.Now()
+	resp, err := p.httpClient.Do(req)
+	p.reportProxy(proxy, resp, err)
+	if err != nil {
+		// A refused redirect is our decision, not a sign of an unhealthy host.
+		var skipped *SkipError
+		if !errors.As(err, &skipped) {
+			p.domainManager.RecordResult(targetURL, 0, time.Since(start), nil)
+		}
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	p.domainManager.RecordResult(targetURL, resp.StatusCode, elapsed, resp.Header)
+
+	body, err := p.Limits.decode(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return &FetchResponse{
+		Body:       body,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		FinalURL:   resp.Request.URL.String(),
+		Redirects:  staticRedirects(resp),
+		Duration:   elapsed,
+	}, nil
+}
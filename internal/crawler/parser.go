@@ -1,16 +1,13 @@
 package crawler
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"fmt"
-	"github.com/chromedp/cdproto/network"
-	"github.com/chromedp/cdproto/page"
-	"github.com/chromedp/chromedp"
 	"go-crawler/pkg/models"
-	"golang.org/x/net/context"
 	"golang.org/x/net/html"
 	"io"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"strings"
@@ -27,15 +24,24 @@ const (
 
 type Parser struct {
 	UserAgent     string
-	allocCtx      context.Context
+	Content       ContentPolicy
+	Validators    ValidatorStore // Optional: revalidates pages crawled before with conditional GETs
+	Limits        BodyLimits
+	Redirects     RedirectPolicy
+	Waits         []WaitCondition // When a render is done; the first condition met ends the wait
+	Proxies       *ProxyPool      // Optional: static fetches and Chrome go through these instead of direct
+	Capture       *APICapture     // Optional: keeps matching XHR/fetch responses of renders on the page
+	Static        Fetcher         // Plain HTTP backend, tried first
+	Dynamic       Fetcher         // Rendering backend, used when decideAction asks for it
+	cookies       *CookieJar
+	tabs          *TabPool
 	domainManager *DomainManager
 	httpClient    *http.Client
 }
 
 func NewParser(userAgent string, allocCtx context.Context, domainMgr *DomainManager) *Parser {
-	return &Parser{
+	p := &Parser{
 		UserAgent:     userAgent,
-		allocCtx:      allocCtx,
 		domainManager: domainMgr,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
@@ -43,9 +49,34 @@ func NewParser(userAgent string, allocCtx context.Context, domainMgr *DomainMana
 				MaxIdleConns:        200,
 				MaxIdleConnsPerHost: 20,
 				IdleConnTimeout:     90 * time.Second,
+				DisableCompression:  true,
+				Proxy:               proxyFromContext,
 			},
 		},
 	}
+	p.httpClient.CheckRedirect = p.checkRedirect
+	p.Static = &httpFetcher{p: p}
+	p.tabs = NewTabPool(allocCtx, TabPoolConfig{})
+	p.Dynamic = &chromeFetcher{p: p}
+	return p
+}
+
+// SetTabPool resizes the pool of Chrome tabs renders are done in. Call it before crawling starts.
+func (p *Parser) SetTabPool(cfg TabPoolConfig) {
+	p.tabs = NewTabPool(p.tabs.allocCtx, cfg)
+}
+
+// Close shuts down the Chrome tabs and browser kept warm for renders.
+func (p *Parser) Close() {
+	p.tabs.Close()
+}
+
+// SetCookieJar makes static fetches and Chrome renders share (and persist) each
+// site's cookies. Policy cookies are then pre-loaded into the jar instead of
+// being sent as-is. Call it before crawling starts.
+func (p *Parser) SetCookieJar(jar *CookieJar) {
+	p.cookies = jar
+	p.httpClient.Jar = jar
 }
 
 func (p *Parser) GetOutBoundLinks(targetURL string) ([]string, error) {
@@ -65,47 +96,111 @@ func (p *Parser) GetOutBoundLinks(targetURL string) ([]string, error) {
 }
 
 func (p *Parser) Parse(targetURL string) (models.PageData, error) {
-	var bodyReader io.ReadCloser
-	var statusCode int
+	return p.ParseContext(context.Background(), targetURL)
+}
+
+// ParseContext is Parse with waits for the host's rate limit (a render after a
+// revalidation, redirect hops) that give up when ctx is done.
+func (p *Parser) ParseContext(ctx context.Context, targetURL string) (models.PageData, error) {
+	var resp *FetchResponse
+	var header http.Header
 	var err error
 	var loadTime time.Duration
+	var truncated bool
+	pageCharset := "utf-8" // Chrome hands us the DOM, which is always UTF-8
 
 	start := time.Now()
 
-	// 1. CHECK CACHE: Is this domain permanently marked as dynamic?
-	if p.domainManager.NeedsDynamic(targetURL) {
-		bodyReader, statusCode, err = p.FetchDynamic(targetURL)
+	// 0. GATE: don't spend a fetch (or a Chrome render) on PDFs, archives, media...
+	if reason := p.Content.CheckURL(targetURL); reason != "" {
+		return models.PageData{URL: targetURL}, &SkipError{URL: targetURL, Reason: reason}
+	}
+	if p.Content.HeadProbe {
+		if reason := p.probeHead(targetURL); reason != "" {
+			return models.PageData{URL: targetURL}, &SkipError{URL: targetURL, Reason: reason}
+		}
+	}
+
+	policy := p.domainManager.Policy(targetURL)
+	fetchMode := FetchAuto
+	if policy != nil {
+		fetchMode = policy.Fetch
+	}
+
+	// Validators from the previous crawl, if any: an unchanged page is a 304 with no body.
+	conditional := p.conditionalHeaders(targetURL)
+
+	// 1. CHECK CACHE: Is this domain forced or permanently marked as dynamic?
+	if fetchMode == FetchDynamic || (fetchMode == FetchAuto && p.domainManager.NeedsDynamic(targetURL)) {
+		// For a page crawled before, a cheap conditional GET first can save the whole
+		// render, and gives us the validators Chrome doesn't expose.
+		if conditional != nil {
+			var unchanged bool
+			var data models.PageData
+			if data, header, unchanged = p.revalidate(ctx, targetURL, conditional); unchanged {
+				return data, nil
+			}
+			// The render is a second request to the host: wait for its turn again.
+			if err := p.domainManager.Wait(ctx, targetURL); err != nil {
+				return models.PageData{URL: targetURL}, err
+			}
+		}
+		resp, truncated, err = p.fetchRendered(ctx, targetURL)
 	} else {
 		// 2. ATTEMPT STATIC FETCH
-		bodyReader, statusCode, err = p.FetchStatic(targetURL)
+		resp, err = p.Static.Fetch(FetchRequest{URL: targetURL, Header: conditional, Ctx: ctx})
+		if err == nil && resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			return notModified(targetURL, resp.Header, conditional), nil
+		}
 
 		// 3. ANALYZE STATIC RESULT
 		if err == nil {
-			bodyBytes, readErr := io.ReadAll(bodyReader)
+			header = resp.Header
+			statusCode := resp.StatusCode
+			bodyReader, reason := p.gateBody(resp.Body, header)
+			if reason != "" {
+				// Abort mid-stream: closing now stops the rest of the download.
+				bodyReader.Close()
+				return models.PageData{URL: targetURL, StatusCode: statusCode}, &SkipError{URL: targetURL, Reason: reason}
+			}
+
+			bodyBytes, cut, readErr := p.Limits.read(targetURL, bodyReader)
 			bodyReader.Close()
 
 			if readErr != nil {
-				return models.PageData{URL: targetURL}, readErr
+				return models.PageData{URL: targetURL, StatusCode: statusCode}, readErr
 			}
+			truncated = cut || resp.Truncated
 
 			// ASK THE JUDGE: What should we do with this body?
-			action := p.decideAction(bodyBytes, statusCode)
+			// (unless the domain policy forbids Chrome for this host)
+			action := ActionUseStatic
+			if fetchMode == FetchAuto {
+				action = p.decideAction(bodyBytes, statusCode)
+			}
 
 			switch action {
 
 			case ActionMarkDynamic:
 				fmt.Printf("[SmartParse] HARD trigger for %s. Marking Domain as Dynamic.\n", targetURL)
-				//p.domainManager.MarkDynamic(targetURL)
+				p.domainManager.MarkDynamic(targetURL)
 				// Fallthrough to retry...
-				bodyReader, statusCode, err = p.FetchDynamic(targetURL)
+				resp, truncated, err = p.fetchRendered(ctx, targetURL)
 
 			case ActionRetryOneOff:
 				fmt.Printf("[SmartParse] SOFT trigger (length/heuristic) for %s. Retrying Dynamic (One-off).\n", targetURL)
-				bodyReader, statusCode, err = p.FetchDynamic(targetURL)
+				resp, truncated, err = p.fetchRendered(ctx, targetURL)
 
 			case ActionUseStatic:
-				// It was good! Restore the reader for extraction.
-				bodyReader = io.NopCloser(bytes.NewReader(bodyBytes))
+				// It was good! Restore the reader for extraction. Only a 2xx page
+				// shows static works again; error pages are used as they are.
+				if fetchMode == FetchAuto && statusCode >= 200 && statusCode < 300 {
+					p.domainManager.RecordStatic(targetURL)
+				}
+				decoded, name := toUTF8(bodyBytes, header.Get("Content-Type"))
+				pageCharset = name
+				resp.Body = io.NopCloser(bytes.NewReader(decoded))
 			}
 		}
 	}
@@ -115,176 +210,93 @@ func (p *Parser) Parse(targetURL string) (models.PageData, error) {
 	if err != nil {
 		return models.PageData{URL: targetURL}, err
 	}
-	defer bodyReader.Close()
+	defer resp.Body.Close()
 
-	// 4. EXTRACT CONTENT
-	data, err := p.Extract(bodyReader, targetURL)
+	// 4. EXTRACT CONTENT (relative links resolve against where we ended up, not where we started)
+	data, err := p.Extract(resp.Body, resp.FinalURL)
 	if err != nil {
-		return models.PageData{URL: targetURL, StatusCode: statusCode}, err
+		return models.PageData{URL: targetURL, StatusCode: resp.StatusCode}, err
 	}
 
 	data.LoadTime = loadTime
-	data.StatusCode = statusCode
+	data.StatusCode = resp.StatusCode
+	data.Charset = pageCharset
+	data.Truncated = truncated
+	data.Redirects = resp.Redirects
+	data.WaitedFor = resp.WaitedFor
+	data.APIResponses = resp.APIResponses
+	data.APICaptured = resp.APICaptured
+
+	// 5. APPLY HEADER DIRECTIVES (X-Robots-Tag and validators only come from static responses)
+	data.ETag, data.LastModified = validators(header)
+	if header != nil {
+		noIndex, noFollow := parseXRobotsTag(header, p.domainManager.robots.Agent)
+		data.NoIndex = data.NoIndex || noIndex
+		data.NoFollow = data.NoFollow || noFollow
+	}
 
 	return data, nil
 }
 
-func (p *Parser) FetchStatic(targetURL string) (io.ReadCloser, int, error) {
-	req, err := http.NewRequest("GET", targetURL, nil)
+// FetchStatic downloads targetURL with the static backend.
+func (p *Parser) FetchStatic(targetURL string) (io.ReadCloser, int, http.Header, error) {
+	resp, err := p.Static.Fetch(FetchRequest{URL: targetURL})
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
+	return resp.Body, resp.StatusCode, resp.Header, nil
+}
 
-	req.Header.Set("User-Agent", p.UserAgent)
-
-	resp, err := p.httpClient.Do(req)
+// FetchDynamic renders targetURL with the dynamic backend.
+func (p *Parser) FetchDynamic(targetURL string) (io.ReadCloser, int, error) {
+	resp, err := p.Dynamic.Fetch(FetchRequest{URL: targetURL})
 	if err != nil {
 		return nil, 0, err
 	}
-
 	return resp.Body, resp.StatusCode, nil
 }
 
-const (
-	// Removes the "I am a robot" flag
-	scriptStealth = `
-		Object.defineProperty(navigator, 'webdriver', { get: () => undefined });
-		window.navigator.chrome = { runtime: {} };
-	`
-	// Forces all links into a clean list for the Go parser
-	scriptLinkShim = `(function(){
-		window.scrollTo(0, document.body.scrollHeight);
-		const links = document.querySelectorAll('a[href]');
-		const shim = document.createElement('div');
-		shim.id = 'crawler-shim';
-		shim.style.display = 'none';
-		links.forEach(l => {
-			const a = document.createElement('a');
-			a.href = l.href;
-			a.innerText = 'shim-link';
-			shim.appendChild(a);
-		});
-		document.body.appendChild(shim);
-	})()`
-)
-
-type BrowserProfile struct {
-	UserAgent  string
-	ClientHint string
-}
+// probeHead asks for the headers only. Failures and servers that reject HEAD
+// are not a reason to skip; the GET gets the final say.
+func (p *Parser) probeHead(targetURL string) string {
+	req, err := http.NewRequest("HEAD", targetURL, nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("User-Agent", p.UserAgent)
 
-// Valid Linux Profiles (Matches your Docker Container)
-var linuxProfiles = []BrowserProfile{
-	// Profile 1: Chrome 132 (Latest)
-	{
-		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36",
-		ClientHint: `"Not A(Brand";v="99", "Google Chrome";v="132", "Chromium";v="132"`,
-	},
-	// Profile 2: Chrome 131
-	{
-		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
-		ClientHint: `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
-	},
-	// Profile 3: Chrome 130
-	{
-		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36",
-		ClientHint: `"Chromium";v="130", "Google Chrome";v="130", "Not?A_Brand";v="99"`,
-	},
-}
+	req, proxy := p.proxied(req)
+	resp, err := p.httpClient.Do(req)
+	p.reportProxy(proxy, resp, err)
+	if err != nil {
+		return ""
+	}
+	resp.Body.Close()
 
-func getRandomProfile() BrowserProfile {
-	return linuxProfiles[rand.Intn(len(linuxProfiles))]
+	if resp.StatusCode >= 400 {
+		return ""
+	}
+	return p.Content.CheckHeader(resp.Header)
 }
 
-func (p *Parser) FetchDynamic(targetURL string) (io.ReadCloser, int, error) {
-	profile := getRandomProfile()
-
-	ctx, cancelCtx := chromedp.NewContext(p.allocCtx,
-		chromedp.WithLogf(func(string, ...interface{}) {}),
-	)
-	defer cancelCtx()
-
-	// 4. Timeout (45s)
-	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
-	defer cancel()
-
-	var htmlContent string
-	var pageTitle string
-	var pageText string
-
-	// 5. Run Tasks
-	err := chromedp.Run(ctx,
-		// (A) Inject Stealth (Pre-load)
-		chromedp.ActionFunc(func(c context.Context) error {
-			_, err := page.AddScriptToEvaluateOnNewDocument(scriptStealth).Do(c)
-			return err
-		}),
-
-		network.Enable(),
-		network.SetExtraHTTPHeaders(network.Headers{
-			"Accept-Language": "en-US,en;q=0.9",
-
-			// USE THE MATCHING HINT HERE:
-			"Sec-Ch-Ua": profile.ClientHint,
-
-			"Sec-Ch-Ua-Mobile":          "?0",
-			"Sec-Ch-Ua-Platform":        `"Linux"`, // Always Linux for Docker
-			"Sec-Fetch-Dest":            "document",
-			"Sec-Fetch-Mode":            "navigate",
-			"Sec-Fetch-Site":            "none",
-			"Sec-Fetch-User":            "?1",
-			"Upgrade-Insecure-Requests": "1",
-		}),
-
-		chromedp.EmulateViewport(1920, 1080),
-		chromedp.Navigate(targetURL),
-
-		// 1. Move to a RANDOM point in the "Safe Zone"
-		// We target a box between X:300-500 and Y:300-500
-		chromedp.ActionFunc(func(c context.Context) error {
-			// Randomize X and Y by adding a random number between 0-200
-			x := 300 + rand.Intn(200)
-			y := 300 + rand.Intn(200)
-
-			// Move the mouse to this random spot
-			return chromedp.MouseClickXY(float64(x), float64(y)).Do(c)
-		}),
-
-		// 2. Add "Human Jitter" (Small pauses)
-		// Humans don't click instantly. They hover, then click.
-		chromedp.Sleep(time.Duration(rand.Intn(300)+100)*time.Millisecond),
-
-		// 3. Scroll randomly (Reading behavior)
-		chromedp.ActionFunc(func(c context.Context) error {
-			scrollDistance := 300 + rand.Intn(400) // Scroll between 300px and 700px
-			script := fmt.Sprintf("window.scrollTo({top: %d, behavior: 'smooth'});", scrollDistance)
-			_, err := page.AddScriptToEvaluateOnNewDocument(script).Do(c)
-			return err
-		}),
-
-		chromedp.Sleep(500*time.Millisecond),
-
-		chromedp.Evaluate(`document.title`, &pageTitle),
-		chromedp.Evaluate(`document.body.innerText.substring(0, 150).replace(/\n/g, " ")`, &pageText),
-
-		// (C) Run the Link Shim
-		chromedp.Evaluate(scriptLinkShim, nil),
-
-		// (D) Capture HTML
-		chromedp.OuterHTML(`html`, &htmlContent),
-	)
-
-	if err != nil {
-		return nil, 0, err
+// gateBody checks a GET response before its body is read. Without a Content-Type
+// header, the first 512 bytes are sniffed; the returned reader still yields them.
+func (p *Parser) gateBody(body io.ReadCloser, header http.Header) (io.ReadCloser, string) {
+	if reason := p.Content.CheckHeader(header); reason != "" {
+		return body, reason
+	}
+	if header.Get("Content-Type") != "" || len(p.Content.AllowedTypes) == 0 {
+		return body, ""
 	}
 
-	fmt.Printf("\n--- CRAWLER REPORT ---\n")
-	fmt.Printf("URL: %s\n", targetURL)
-	fmt.Printf("PAGE TITLE:  [%s]\n", pageTitle)
-	fmt.Printf("PAGE TEXT:   [%s]\n", pageText)
-	fmt.Printf("----------------------\n\n")
+	buffered := bufio.NewReader(body)
+	head, _ := buffered.Peek(512)
+	return readCloser{Reader: buffered, Closer: body}, p.Content.CheckSniffed(head)
+}
 
-	return io.NopCloser(strings.NewReader(htmlContent)), 200, nil
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
 func (p *Parser) decideAction(html []byte, statusCode int) FetchAction {
@@ -373,33 +385,49 @@ func (p *Parser) Extract(r io.Reader, baseURL string) (models.PageData, error) {
 	}
 
 	var links []string
+	var nofollowLinks []string
 	var textBuilder strings.Builder
 
-	var visit func(*html.Node)
-	visit = func(n *html.Node) {
+	var visit func(n *html.Node, inHead bool)
+	visit = func(n *html.Node, inHead bool) {
 		if n.Type == html.ElementNode && n.Data == "head" {
-			return
+			inHead = true
 		}
 
 		// 1. Find Title
-		if n.Type == html.ElementNode && n.Data == "title" && n.FirstChild != nil {
+		if n.Type == html.ElementNode && n.Data == "title" && n.FirstChild != nil && data.Title == "" {
 			data.Title = n.FirstChild.Data
 		}
 
-		// 2. Find Links
+		// 2. Find Robots Directives (<meta name="robots">, <link rel="canonical">)
+		if n.Type == html.ElementNode && n.Data == "meta" && strings.EqualFold(getAttr(n, "name"), "robots") {
+			noIndex, noFollow := parseRobotsDirectives(getAttr(n, "content"))
+			data.NoIndex = data.NoIndex || noIndex
+			data.NoFollow = data.NoFollow || noFollow
+		}
+		if n.Type == html.ElementNode && n.Data == "link" && hasToken(getAttr(n, "rel"), "canonical") && data.CanonicalURL == "" {
+			data.CanonicalURL = resolveURL(baseURL, getAttr(n, "href"))
+		}
+
+		// 3. Find Links (rel="nofollow" links are kept apart so the caller can decide)
 		if n.Type == html.ElementNode && n.Data == "a" {
 			for _, a := range n.Attr {
 				if a.Key == "href" {
 					absoluteURL := resolveURL(baseURL, a.Val)
-					if absoluteURL != "" {
+					if absoluteURL == "" {
+						continue
+					}
+					if hasToken(getAttr(n, "rel"), "nofollow") {
+						nofollowLinks = append(nofollowLinks, absoluteURL)
+					} else {
 						links = append(links, absoluteURL)
 					}
 				}
 			}
 		}
 
-		// 3. Extract Text (ignoring scripts/styles)
-		if n.Type == html.TextNode {
+		// 4. Extract Text (ignoring head/scripts/styles)
+		if n.Type == html.TextNode && !inHead {
 			parent := n.Parent
 			if parent != nil &&
 				parent.Data != "script" &&
@@ -414,14 +442,15 @@ func (p *Parser) Extract(r io.Reader, baseURL string) (models.PageData, error) {
 		}
 
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			visit(c)
+			visit(c, inHead)
 		}
 	}
 
-	visit(doc)
+	visit(doc, false)
 
 	data.TextContent = textBuilder.String()
 	data.OutboundLinks = links
+	data.NofollowLinks = nofollowLinks
 	return data, nil
 }
 
@@ -437,3 +466,65 @@ func resolveURL(base, href string) string {
 	}
 	return baseURL.ResolveReference(u).String()
 }
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether a space-separated attribute (e.g. rel="noopener nofollow") contains token.
+func hasToken(attr, token string) bool {
+	for _, t := range strings.Fields(attr) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRobotsDirectives reads a meta robots / X-Robots-Tag value such as "noindex, nofollow".
+func parseRobotsDirectives(content string) (noIndex, noFollow bool) {
+	for _, d := range strings.Split(content, ",") {
+		switch strings.ToLower(strings.TrimSpace(d)) {
+		case "noindex":
+			noIndex = true
+		case "nofollow":
+			noFollow = true
+		case "none":
+			noIndex, noFollow = true, true
+		}
+	}
+	return noIndex, noFollow
+}
+
+// parseXRobotsTag applies every X-Robots-Tag header line that targets all crawlers
+// or our robots agent token. Lines scoped to another agent (e.g. "googlebot:
+// noindex") are ignored.
+func parseXRobotsTag(header http.Header, agent string) (noIndex, noFollow bool) {
+	for _, value := range header.Values("X-Robots-Tag") {
+		if scope, directives, found := strings.Cut(value, ":"); found && !strings.Contains(scope, ",") && !isRobotsDirective(scope) {
+			if agent == "" || !strings.EqualFold(strings.TrimSpace(scope), agent) {
+				continue
+			}
+			value = directives
+		}
+		i, f := parseRobotsDirectives(value)
+		noIndex = noIndex || i
+		noFollow = noFollow || f
+	}
+	return noIndex, noFollow
+}
+
+// isRobotsDirective tells a directive that carries its own colon ("unavailable_after: <date>")
+// apart from an agent prefix.
+func isRobotsDirective(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "all", "noindex", "nofollow", "none", "noarchive", "nosnippet", "unavailable_after":
+		return true
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package crawler
 
 import (
+	"errors"
+	"go-crawler/pkg/models"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -70,3 +74,116 @@ func TestParser_Extract(t *testing.T) {
 		}
 	}
 }
+
+func TestParser_ExtractRobotsDirectives(t *testing.T) {
+	p := &Parser{}
+
+	rawHTML := `
+		<html>
+		<head>
+			<title>Duplicate</title>
+			<meta name="ROBOTS" content="noindex, follow">
+			<link rel="canonical" href="/original">
+		</head>
+		<body>
+			<a href="/followed">Followed</a>
+			<a href="/sponsored" rel="sponsored nofollow">Ad</a>
+		</body>
+		</html>
+	`
+
+	data, err := p.Extract(strings.NewReader(rawHTML), "https://example.com/copy?ref=1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !data.NoIndex || data.NoFollow {
+		t.Errorf("Directive mismatch. Got NoIndex=%v NoFollow=%v", data.NoIndex, data.NoFollow)
+	}
+	if data.CanonicalURL != "https://example.com/original" {
+		t.Errorf("Canonical mismatch. Got: %q", data.CanonicalURL)
+	}
+	if len(data.OutboundLinks) != 1 || len(data.NofollowLinks) != 1 {
+		t.Fatalf("Expected 1 followed and 1 nofollow link, got %v / %v", data.OutboundLinks, data.NofollowLinks)
+	}
+
+	links, store := CompliancePolicy{HonorCanonical: true, HonorNoIndex: true, HonorLinkNofollow: true}.Apply(&data)
+	if store {
+		t.Error("Noindex page should not be stored")
+	}
+	if len(links) != 1 || links[0] != "https://example.com/followed" {
+		t.Errorf("Nofollow link should not be followed, got %v", links)
+	}
+	if data.URL != "https://example.com/original" {
+		t.Errorf("Page should be stored under its canonical URL, got %q", data.URL)
+	}
+}
+
+func TestCompliancePolicy_CanonicalStaysOnSite(t *testing.T) {
+	policy := CompliancePolicy{HonorCanonical: true}
+	tests := []struct {
+		url, canonical, want string
+	}{
+		{"https://example.com/a", "https://www.example.com/b", "https://www.example.com/b"},
+		{"https://blog.example.co.uk/a", "https://example.co.uk/a", "https://example.co.uk/a"},
+		{"https://example.com/a", "https://victim.org/", "https://example.com/a"},
+		{"https://example.com/a", "ftp://example.com/a", "https://example.com/a"},
+	}
+	for _, tt := range tests {
+		data := models.PageData{URL: tt.url, CanonicalURL: tt.canonical}
+		policy.Apply(&data)
+		if data.URL != tt.want {
+			t.Errorf("Canonical %s of %s: stored under %s, expected %s", tt.canonical, tt.url, data.URL, tt.want)
+		}
+		if data.FinalURL() != tt.url {
+			t.Errorf("Canonical %s of %s: FinalURL became %s", tt.canonical, tt.url, data.FinalURL())
+		}
+	}
+}
+
+func TestParseXRobotsTag(t *testing.T) {
+	header := http.Header{}
+	header.Add("X-Robots-Tag", "googlebot: noindex")
+	header.Add("X-Robots-Tag", "nofollow, unavailable_after: 25 Jun 2010 15:00:00 PST")
+
+	noIndex, noFollow := parseXRobotsTag(header, "MyPageCrawler")
+	if noIndex {
+		t.Error("Agent-scoped noindex for another crawler should be ignored")
+	}
+	if !noFollow {
+		t.Error("Expected nofollow from the unscoped header line")
+	}
+
+	ours := http.Header{}
+	ours.Add("X-Robots-Tag", "mypagecrawler: noindex")
+	if noIndex, _ := parseXRobotsTag(ours, "MyPageCrawler"); !noIndex {
+		t.Error("Expected a line scoped to our agent token to be honoured")
+	}
+}
+
+func TestParser_ParseSkipsNonHTML(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.7"))
+	}))
+	defer server.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	p.Content = NewContentPolicy([]string{"zip"}, nil, []string{"text/html"}, false, 0)
+
+	var skipped *SkipError
+	if _, err := p.Parse(server.URL + "/archive.zip"); !errors.As(err, &skipped) {
+		t.Errorf("Expected SkipError for a skipped extension, got %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("Skipped extension should not be fetched, server saw %d requests", hits)
+	}
+
+	if _, err := p.Parse(server.URL + "/report"); !errors.As(err, &skipped) {
+		t.Errorf("Expected SkipError for a PDF body, got %v", err)
+	} else if !strings.Contains(skipped.Reason, "application/pdf") {
+		t.Errorf("Reason should name the content type, got %q", skipped.Reason)
+	}
+}
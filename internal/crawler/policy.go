@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"fmt"
+	"go-crawler/pkg/models"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Fetch modes a DomainPolicy can force.
+const (
+	FetchAuto    = ""        // Static first, Chrome when decideAction says so
+	FetchStatic  = "static"  // Never launch Chrome for this host
+	FetchDynamic = "dynamic" // Always render with Chrome
+)
+
+// DomainPolicy overrides crawl settings for every host matching one of Hosts.
+// Zero values mean "use the global setting".
+type DomainPolicy struct {
+	Hosts          []string          `json:"hosts" yaml:"hosts"` // Exact hosts or globs like "*.example.com"
+	RateLimit      string            `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+	GroupRateLimit string            `json:"group_rate_limit,omitempty" yaml:"group_rate_limit,omitempty"` // Pace of the host's politeness group
+	MaxConcurrency int               `json:"max_concurrency,omitempty" yaml:"max_concurrency,omitempty"`
+	Fetch          string            `json:"fetch,omitempty" yaml:"fetch,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Cookies        map[string]string `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+	MaxPages       int               `json:"max_pages,omitempty" yaml:"max_pages,omitempty"`
+	AllowedPaths   []string          `json:"allowed_paths,omitempty" yaml:"allowed_paths,omitempty"` // Regexes on the URL path
+	Wait           []string          `json:"wait,omitempty" yaml:"wait,omitempty"`                   // Render wait conditions, see ParseWaitCondition
+
+	rateLimit      time.Duration
+	groupRateLimit time.Duration
+	allowedPaths   []*regexp.Regexp
+	waits          []WaitCondition
+}
+
+// PolicySet is an ordered list of policies; the first one matching a host wins.
+type PolicySet struct {
+	Policies []*DomainPolicy `json:"policies" yaml:"policies"`
+}
+
+// LoadPolicyFile reads a PolicySet from a .json, .yaml or .yml file.
+func LoadPolicyFile(filename string) (*PolicySet, error) {
+	var set PolicySet
+	if err := decodeConfigFile(filename, &set); err != nil {
+		return nil, err
+	}
+	for i, p := range set.Policies {
+		if err := p.compile(); err != nil {
+			return nil, fmt.Errorf("invalid policy [%d] in %s: %w", i, filename, err)
+		}
+	}
+	return &set, nil
+}
+
+func (p *DomainPolicy) compile() error {
+	if len(p.Hosts) == 0 {
+		return fmt.Errorf("no hosts")
+	}
+	for i, h := range p.Hosts {
+		p.Hosts[i] = strings.ToLower(h)
+		if _, err := path.Match(p.Hosts[i], ""); err != nil {
+			return fmt.Errorf("host pattern %q: %w", h, err)
+		}
+	}
+	if p.RateLimit != "" {
+		d, err := time.ParseDuration(p.RateLimit)
+		if err != nil {
+			return fmt.Errorf("rate_limit: %w", err)
+		}
+		p.rateLimit = d
+	}
+	if p.GroupRateLimit != "" {
+		d, err := time.ParseDuration(p.GroupRateLimit)
+		if err != nil {
+			return fmt.Errorf("group_rate_limit: %w", err)
+		}
+		p.groupRateLimit = d
+	}
+	switch p.Fetch {
+	case FetchAuto, FetchStatic, FetchDynamic:
+	default:
+		return fmt.Errorf("fetch must be %q or %q, got %q", FetchStatic, FetchDynamic, p.Fetch)
+	}
+	for _, pattern := range p.AllowedPaths {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("allowed_paths %q: %w", pattern, err)
+		}
+		p.allowedPaths = append(p.allowedPaths, re)
+	}
+	for _, spec := range p.Wait {
+		c, err := ParseWaitCondition(spec)
+		if err != nil {
+			return err
+		}
+		p.waits = append(p.waits, c)
+	}
+	return nil
+}
+
+// Lookup returns the policy for the link's host, or nil. It is safe on a nil set.
+func (s *PolicySet) Lookup(link string) *DomainPolicy {
+	if s == nil {
+		return nil
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil
+	}
+	return s.lookupHost(u.Hostname())
+}
+
+func (s *PolicySet) lookupHost(host string) *DomainPolicy {
+	if s == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	for _, p := range s.Policies {
+		for _, pattern := range p.Hosts {
+			if matched, _ := path.Match(pattern, host); matched {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// AllowsPath reports whether the URL path matches one of AllowedPaths (or there are none).
+func (p *DomainPolicy) AllowsPath(urlPath string) bool {
+	if p == nil || len(p.allowedPaths) == 0 {
+		return true
+	}
+	for _, re := range p.allowedPaths {
+		if re.MatchString(urlPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyFilter drops links outside their host's allowed_paths.
+type PolicyFilter struct {
+	Policies *PolicySet
+}
+
+func (filter PolicyFilter) Filter(source models.DataSource, link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return filter.Policies.lookupHost(u.Hostname()).AllowsPath(u.Path)
+}
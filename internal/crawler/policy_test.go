@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"go-crawler/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPolicyFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policies.yaml")
+	err := os.WriteFile(filename, []byte(`
+policies:
+  - hosts: ["*.example.com", example.com]
+    rate_limit: 500ms
+    group_rate_limit: 2s
+    fetch: static
+    max_pages: 2
+    allowed_paths: ['^/blog/']
+  - hosts: ["*"]
+    fetch: dynamic
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err := LoadPolicyFile(filename)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if p := policies.Lookup("https://shop.example.com/x"); p == nil || p.Fetch != FetchStatic || p.rateLimit.Milliseconds() != 500 || p.groupRateLimit.Seconds() != 2 {
+		t.Errorf("Expected the example.com policy for a subdomain, got %+v", p)
+	}
+	if p := policies.Lookup("https://other.org/"); p == nil || p.Fetch != FetchDynamic {
+		t.Errorf("Expected the catch-all policy, got %+v", p)
+	}
+
+	filter := PolicyFilter{Policies: policies}
+	if !filter.Filter(models.None, "https://example.com/blog/post") || filter.Filter(models.None, "https://example.com/shop") {
+		t.Error("PolicyFilter should only accept allowed_paths")
+	}
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{}, HostStateConfig{})
+	d.SetPolicies(policies)
+	allowed := 0
+	for _, host := range []string{"example.com", "shop.example.com", "blog.example.com", "example.com"} {
+		if d.AllowPage("https://" + host + "/blog/post") {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Expected max_pages to allow 2 pages across the policy's hosts, got %d", allowed)
+	}
+}
+
+func TestLoadPolicyFile_Invalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policies.json")
+	if err := os.WriteFile(filename, []byte(`{"policies": [{"hosts": ["a.com"], "fetch": "sometimes"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicyFile(filename); err == nil {
+		t.Error("Expected an error for an unknown fetch mode")
+	}
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"go-crawler/internal"
+	"net"
+	"strings"
+	"time"
+)
+
+// GroupMode decides which hosts share a politeness group. Hosts in one group
+// (e.g. hundreds of virtual hosts on one shared-hosting IP) share a single
+// group limiter on top of their own per-host throttle.
+type GroupMode int
+
+const (
+	GroupByHost   GroupMode = iota // No grouping: every host stands alone
+	GroupByIP                      // Hosts resolving to the same IP address
+	GroupBySubnet                  // Hosts in the same /24 (IPv4) or /64 (IPv6)
+)
+
+func ParseGroupMode(s string) (GroupMode, error) {
+	switch strings.ToLower(s) {
+	case "", "host":
+		return GroupByHost, nil
+	case "ip":
+		return GroupByIP, nil
+	case "subnet":
+		return GroupBySubnet, nil
+	default:
+		return 0, fmt.Errorf("unknown politeness grouping %q (use host, ip or subnet)", s)
+	}
+}
+
+// hostGrouper maps hosts to group keys, caching DNS answers.
+type hostGrouper struct {
+	mode   GroupMode
+	ttl    time.Duration
+	lookup func(ctx context.Context, host string) ([]net.IP, error)
+
+	cache *internal.LRU[string, dnsAnswer]
+}
+
+type dnsAnswer struct {
+	key     string
+	expires time.Time
+}
+
+func newHostGrouper(mode GroupMode, ttl time.Duration, maxHosts int) *hostGrouper {
+	return &hostGrouper{
+		mode: mode,
+		ttl:  ttl,
+		lookup: func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+		cache: internal.NewLRU[string, dnsAnswer](maxHosts, ttl, nil),
+	}
+}
+
+// key returns the politeness group for a hostname. Hosts that fail to resolve
+// are their own group, so a DNS hiccup never merges unrelated hosts.
+func (g *hostGrouper) key(hostname string) string {
+	if g.mode == GroupByHost {
+		return hostname
+	}
+
+	answer, cached := g.cache.Get(hostname)
+	if cached && time.Now().Before(answer.expires) {
+		return answer.key
+	}
+
+	key := "host:" + hostname
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if ips, err := g.lookup(ctx, hostname); err == nil && len(ips) > 0 {
+		key = g.groupFor(ips[0])
+	}
+
+	g.cache.Put(hostname, dnsAnswer{key: key, expires: time.Now().Add(g.ttl)})
+	return key
+}
+
+func (g *hostGrouper) groupFor(ip net.IP) string {
+	if g.mode == GroupBySubnet {
+		if v4 := ip.To4(); v4 != nil {
+			return "net:" + v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+		}
+		return "net:" + ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+	}
+	return "ip:" + ip.String()
+}
@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/time/rate"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHostGrouper_Keys(t *testing.T) {
+	lookups := 0
+	fakeDNS := func(ctx context.Context, host string) ([]net.IP, error) {
+		lookups++
+		switch host {
+		case "a.shared.com", "b.shared.com":
+			return []net.IP{net.ParseIP("203.0.113.10")}, nil
+		case "neighbour.com":
+			return []net.IP{net.ParseIP("203.0.113.99")}, nil
+		default:
+			return nil, errors.New("no such host")
+		}
+	}
+
+	byIP := newHostGrouper(GroupByIP, time.Minute, 0)
+	byIP.lookup = fakeDNS
+	if byIP.key("a.shared.com") != byIP.key("b.shared.com") {
+		t.Error("Hosts on the same IP should share a group")
+	}
+	if byIP.key("a.shared.com") == byIP.key("neighbour.com") {
+		t.Error("Different IPs should not share a group in ip mode")
+	}
+	if byIP.key("unresolvable.com") == byIP.key("other-unresolvable.com") {
+		t.Error("Unresolvable hosts must not be merged")
+	}
+
+	bySubnet := newHostGrouper(GroupBySubnet, time.Minute, 0)
+	bySubnet.lookup = fakeDNS
+	if got := bySubnet.key("neighbour.com"); got != "net:203.0.113.0/24" || got != bySubnet.key("a.shared.com") {
+		t.Errorf("Expected both hosts in 203.0.113.0/24, got %q", got)
+	}
+
+	before := lookups
+	bySubnet.key("neighbour.com")
+	if lookups != before {
+		t.Error("Expected the DNS answer to be cached")
+	}
+}
+
+func TestDomainManager_GroupRateLimitPolicy(t *testing.T) {
+	d := NewDomainManager(RateConfig{Grouping: GroupByIP, GroupDelay: 500 * time.Millisecond}, RobotsConfig{}, HostStateConfig{})
+	d.grouper.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		if host == "other.com" {
+			return []net.IP{net.ParseIP("198.51.100.1")}, nil
+		}
+		return []net.IP{net.ParseIP("203.0.113.10")}, nil
+	}
+	d.SetPolicies(&PolicySet{Policies: []*DomainPolicy{{Hosts: []string{"slow.shared.com"}, groupRateLimit: 2 * time.Second}}})
+
+	if got := d.groupLimiter("fast.shared.com").Limit(); got != rate.Every(500*time.Millisecond) {
+		t.Errorf("Expected GROUP_RATE_LIMIT for a group without policy, got %v", got)
+	}
+	// A host whose policy asks for a slower group pace slows its whole group down.
+	d.groupLimiter("slow.shared.com")
+	if got := d.groupLimiter("fast.shared.com").Limit(); got != rate.Every(2*time.Second) {
+		t.Errorf("Expected the group to slow down to the policy's group_rate_limit, got %v", got)
+	}
+	if got := d.groupLimiter("other.com").Limit(); got != rate.Every(500*time.Millisecond) {
+		t.Errorf("Expected other groups to keep GROUP_RATE_LIMIT, got %v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProxyPool_RotationAndEjection(t *testing.T) {
+	pool, err := NewProxyPool([]string{"http://p1:3128", "socks5://p2:1080"}, ProxyPoolConfig{EjectAfter: 2, EjectFor: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, second := pool.Pick("a.com"), pool.Pick("a.com"); first == second {
+		t.Errorf("Round-robin should alternate, got %s twice", first)
+	}
+
+	bad := pool.Pick("a.com")
+	pool.Report(bad, false)
+	pool.Report(bad, false)
+	for i := 0; i < 4; i++ {
+		if got := pool.Pick("b.com"); got == bad {
+			t.Fatalf("Ejected proxy %s was picked", bad)
+		}
+	}
+
+	if _, err := NewProxyPool([]string{"ftp://p3"}, ProxyPoolConfig{}); err == nil {
+		t.Error("Expected an unsupported scheme to be rejected")
+	}
+	if pool, _ := NewProxyPool(nil, ProxyPoolConfig{}); pool != nil || pool.Pick("a.com") != nil {
+		t.Error("An empty pool should be nil and connect directly")
+	}
+}
+
+func TestProxyPool_Sticky(t *testing.T) {
+	pool, _ := NewProxyPool([]string{"http://p1:3128", "http://p2:3128", "http://p3:3128"}, ProxyPoolConfig{Rotation: RotateSticky, EjectAfter: 1})
+	proxy := pool.Pick("a.com")
+	for i := 0; i < 5; i++ {
+		pool.Pick(fmt.Sprintf("other%d.com", i))
+		if got := pool.Pick("a.com"); got != proxy {
+			t.Fatalf("Expected a.com to stay on %s, got %s", proxy, got)
+		}
+	}
+	pool.Report(proxy, false)
+	if got := pool.Pick("a.com"); got == proxy {
+		t.Error("Expected a.com to move off its ejected proxy")
+	}
+}
+
+func TestParser_FetchStaticThroughProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String() // Absolute-form request line = we are the proxy
+		io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	p.Proxies, _ = NewProxyPool([]string{proxy.URL}, ProxyPoolConfig{})
+
+	body, _, _, err := p.FetchStatic("http://unreachable.example/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if proxied != "http://unreachable.example/page" {
+		t.Errorf("Expected the request to go through the proxy, proxy saw %q", proxied)
+	}
+}
+
+func TestDomainManager_RobotsThroughProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		io.WriteString(w, "User-agent: *\nSitemap: http://unreachable.example/sitemap.xml\n")
+	}))
+	defer proxy.Close()
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{})
+	proxies, _ := NewProxyPool([]string{proxy.URL}, ProxyPoolConfig{})
+	d.SetProxies(proxies)
+
+	if got := d.Sitemaps("http://unreachable.example/page"); len(got) != 1 {
+		t.Errorf("Expected the sitemap from robots.txt fetched via the proxy, got %v", got)
+	}
+	if proxied != "http://unreachable.example/robots.txt" {
+		t.Errorf("Expected robots.txt to go through the proxy, proxy saw %q", proxied)
+	}
+}
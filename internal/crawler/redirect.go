@@ -0,0 +1,111 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"go-crawler/pkg/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// RedirectPolicy controls which redirects a fetch follows.
+type RedirectPolicy struct {
+	MaxHops   int       // Redirects followed before giving up (0 = 10, like net/http)
+	CrossHost bool      // Follow redirects to another site, not just within the registrable domain
+	Scope     URLFilter // Hop targets the crawl filter would reject are not followed (nil = any)
+}
+
+func (r RedirectPolicy) maxHops() int {
+	if r.MaxHops <= 0 {
+		return 10
+	}
+	return r.MaxHops
+}
+
+// checkRedirect is the http.Client hook enforcing the RedirectPolicy. Refusals
+// are SkipErrors, so they end up in the suppression log like other skips.
+func (p *Parser) checkRedirect(req *http.Request, via []*http.Request) error {
+	return p.checkHop(req.Context(), via[0].URL.String(), req.URL.String(), len(via))
+}
+
+// checkHop decides whether the hop'th redirect of origin, to 'to', is followed.
+// The target gets the same checks a queued link does before it is fetched:
+// robots.txt, the crawl scope, its policy's max_pages and its host's rate limit.
+func (p *Parser) checkHop(ctx context.Context, origin, to string, hop int) error {
+	if hop > p.Redirects.maxHops() {
+		return &SkipError{URL: origin, Reason: fmt.Sprintf("more than %d redirects", p.Redirects.maxHops())}
+	}
+	if err := p.Redirects.checkHost(origin, to); err != nil {
+		return err
+	}
+	if !p.domainManager.IsAllowed(to) {
+		return &SkipError{URL: origin, Reason: fmt.Sprintf("redirect target disallowed by robots.txt (%s)", to)}
+	}
+	if p.Redirects.Scope != nil && !p.Redirects.Scope.Filter(models.None, to) {
+		return &SkipError{URL: origin, Reason: fmt.Sprintf("redirects out of scope (%s)", to)}
+	}
+	// The origin was already counted against its own policy.
+	if p.domainManager.Policy(to) != p.domainManager.Policy(origin) && !p.domainManager.AllowPage(to) {
+		return &SkipError{URL: origin, Reason: fmt.Sprintf("redirect target over its policy's max_pages (%s)", to)}
+	}
+	return p.domainManager.Wait(ctx, to)
+}
+
+// navGuard vets the main-frame navigations of one render as Chrome makes them:
+// the first is the page itself, every later one a redirect hop, HTTP or client-side.
+type navGuard struct {
+	ctx    context.Context
+	p      *Parser
+	origin string
+
+	mu          sync.Mutex
+	navigations int
+	refused     error // The first refusal; Fetch returns it instead of the page
+}
+
+func (g *navGuard) check(link string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.navigations++
+	if g.navigations > 1 && g.refused == nil {
+		g.refused = g.p.checkHop(g.ctx, g.origin, link, g.navigations-1)
+	}
+	return g.refused
+}
+
+func (g *navGuard) err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.refused
+}
+
+// checkHost refuses a redirect from 'from' to another site unless CrossHost is
+// set. Hosts of one registrable domain (example.com, www.example.com) are one site.
+func (r RedirectPolicy) checkHost(from, to string) error {
+	if r.CrossHost {
+		return nil
+	}
+	fromURL, err1 := url.Parse(from)
+	toURL, err2 := url.Parse(to)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	fromHost, toHost := strings.ToLower(fromURL.Hostname()), strings.ToLower(toURL.Hostname())
+	if fromHost == toHost || registrableDomain(fromHost) == registrableDomain(toHost) {
+		return nil
+	}
+	return &SkipError{URL: from, Reason: fmt.Sprintf("redirects to another host (%s)", toURL.Hostname())}
+}
+
+// staticRedirects rebuilds the redirect chain of a net/http response: every request
+// made because of a redirect points back at the response that caused it.
+func staticRedirects(resp *http.Response) []models.Redirect {
+	var hops []models.Redirect
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		hop := models.Redirect{URL: r.Response.Request.URL.String(), StatusCode: r.Response.StatusCode}
+		hops = append([]models.Redirect{hop}, hops...)
+	}
+	return hops
+}
@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParser_ParseFollowsRedirectChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/a", http.RedirectHandler("/b", http.StatusMovedPermanently))
+	mux.Handle("/b", http.RedirectHandler("/docs/final", http.StatusFound))
+	mux.HandleFunc("/docs/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>` + strings.Repeat("Final page. ", 20) + `</p><a href="next">Next</a></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+
+	data, err := p.Parse(server.URL + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.URL != server.URL+"/docs/final" {
+		t.Errorf("Expected the page under its final URL, got %s", data.URL)
+	}
+	if len(data.Redirects) != 2 ||
+		data.Redirects[0].URL != server.URL+"/a" || data.Redirects[0].StatusCode != http.StatusMovedPermanently ||
+		data.Redirects[1].URL != server.URL+"/b" || data.Redirects[1].StatusCode != http.StatusFound {
+		t.Errorf("Unexpected redirect chain: %+v", data.Redirects)
+	}
+	if len(data.OutboundLinks) != 1 || data.OutboundLinks[0] != server.URL+"/docs/next" {
+		t.Errorf("Expected links resolved against the final URL, got %v", data.OutboundLinks)
+	}
+
+	p.Redirects = RedirectPolicy{MaxHops: 1, CrossHost: true}
+	var skipped *SkipError
+	if _, err := p.Parse(server.URL + "/a"); !errors.As(err, &skipped) {
+		t.Errorf("Expected a SkipError past MaxHops, got %v", err)
+	}
+}
+
+func TestRedirectPolicy_CheckHost(t *testing.T) {
+	strict := RedirectPolicy{}
+	if err := strict.checkHost("http://example.com/a", "https://EXAMPLE.com/b"); err != nil {
+		t.Errorf("Same host on another scheme should be allowed, got %v", err)
+	}
+	if err := strict.checkHost("https://example.com/", "https://www.example.com/"); err != nil {
+		t.Errorf("Apex to www is the same site, got %v", err)
+	}
+	if err := strict.checkHost("https://shop.example.co.uk/", "https://example.co.uk/"); err != nil {
+		t.Errorf("Subdomain to apex is the same site, got %v", err)
+	}
+	if err := strict.checkHost("https://a.github.io/", "https://b.github.io/"); err == nil {
+		t.Error("Expected hosts under a public suffix to be different sites")
+	}
+	if err := strict.checkHost("http://example.com/a", "http://tracker.net/b"); err == nil {
+		t.Error("Expected a cross-host redirect to be refused")
+	}
+	if err := (RedirectPolicy{CrossHost: true}).checkHost("http://example.com/a", "http://tracker.net/b"); err != nil {
+		t.Errorf("CrossHost should allow it, got %v", err)
+	}
+}
+
+func TestParser_RedirectTargetsAreVetted(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.Handle("/to-private", http.RedirectHandler("/private", http.StatusFound))
+	mux.Handle("/to-other", http.RedirectHandler("/other", http.StatusFound))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>` + strings.Repeat("Some page. ", 20) + `</p></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	var skipped *SkipError
+
+	// 127.0.0.1 and localhost are different hosts to the policy.
+	crossHost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	mux.Handle("/to-localhost", http.RedirectHandler(crossHost+"/", http.StatusFound))
+	if _, err := p.Parse(server.URL + "/to-localhost"); !errors.As(err, &skipped) {
+		t.Errorf("Expected a cross-host redirect to be skipped by default, got %v", err)
+	}
+	if _, err := p.Parse(server.URL + "/to-private"); !errors.As(err, &skipped) || !strings.Contains(skipped.Reason, "robots.txt") {
+		t.Errorf("Expected a redirect into a disallowed path to be skipped, got %v", err)
+	}
+
+	p.Redirects.Scope = filterFunc(func(link string) bool { return !strings.HasSuffix(link, "/other") })
+	if _, err := p.Parse(server.URL + "/to-other"); !errors.As(err, &skipped) || !strings.Contains(skipped.Reason, "scope") {
+		t.Errorf("Expected a redirect out of scope to be skipped, got %v", err)
+	}
+}
+
+func TestNavGuard_VetsEveryNavigationAfterTheFirst(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler()) // No robots.txt: all allowed
+	defer server.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	p.Redirects.Scope = filterFunc(func(link string) bool { return !strings.HasSuffix(link, "/out") })
+
+	guard := &navGuard{ctx: context.Background(), p: p, origin: server.URL + "/a"}
+	if err := guard.check(server.URL + "/a"); err != nil {
+		t.Fatalf("The page's own navigation must pass, got %v", err)
+	}
+	if err := guard.check(server.URL + "/b"); err != nil {
+		t.Fatalf("Expected the first hop to pass, got %v", err)
+	}
+	if err := guard.check(server.URL + "/out"); err == nil {
+		t.Fatal("Expected a hop out of scope to be refused")
+	}
+	if err := guard.check(server.URL + "/c"); err == nil || guard.err() == nil {
+		t.Error("Expected the refusal to stick for the rest of the render")
+	}
+}
@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-crawler/pkg/models"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotRecorded is returned by a replay-only ReplayFetcher for a URL it has no recording of.
+var ErrNotRecorded = errors.New("no recorded response")
+
+// ReplayFetcher serves responses recorded in Dir, and records the ones it doesn't
+// have yet by fetching them with Next. With a nil Next it only replays, which
+// makes the pages of a crawl reproducible (debugging a parse, tests). Only page
+// fetches go through it: robots.txt, sitemaps and the HEAD probe before a render
+// still hit the network.
+type ReplayFetcher struct {
+	Dir    string
+	Next   Fetcher
+	Limits BodyLimits // Applied to what is read from Next, as the Parser would
+}
+
+type recording struct {
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code"`
+	Header     http.Header       `json:"header,omitempty"`
+	FinalURL   string            `json:"final_url"`
+	Redirects  []models.Redirect `json:"redirects,omitempty"`
+	Body       []byte            `json:"body"`
+
+	WaitedFor    string               `json:"waited_for,omitempty"`
+	APIResponses []models.APIResponse `json:"api_responses,omitempty"`
+	APICaptured  bool                 `json:"api_captured,omitempty"`
+	Truncated    bool                 `json:"truncated,omitempty"`
+}
+
+func (f *ReplayFetcher) Fetch(req FetchRequest) (*FetchResponse, error) {
+	filename := f.filename(req.URL)
+	if raw, err := os.ReadFile(filename); err == nil {
+		var rec recording
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return nil, fmt.Errorf("recording %s: %w", filename, err)
+		}
+		return &FetchResponse{
+			Body:         io.NopCloser(bytes.NewReader(rec.Body)),
+			StatusCode:   rec.StatusCode,
+			Header:       rec.Header,
+			FinalURL:     rec.FinalURL,
+			Redirects:    rec.Redirects,
+			WaitedFor:    rec.WaitedFor,
+			APIResponses: rec.APIResponses,
+			APICaptured:  rec.APICaptured,
+			Truncated:    rec.Truncated,
+		}, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if f.Next == nil {
+		return nil, fmt.Errorf("%s: %w", req.URL, ErrNotRecorded)
+	}
+	resp, err := f.Next.Fetch(req)
+	if err != nil || resp.StatusCode == http.StatusNotModified {
+		// A 304 only means something next to the validators that produced it.
+		return resp, err
+	}
+	// An oversized page is cut or skipped here, as the Parser would, and the cut
+	// is recorded so the replay reports it too.
+	body, truncated, err := f.Limits.read(req.URL, resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.Truncated = resp.Truncated || truncated
+
+	rec, err := json.Marshal(recording{
+		URL:          req.URL,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		FinalURL:     resp.FinalURL,
+		Redirects:    resp.Redirects,
+		Body:         body,
+		WaitedFor:    resp.WaitedFor,
+		APIResponses: resp.APIResponses,
+		APICaptured:  resp.APICaptured,
+		Truncated:    resp.Truncated,
+	})
+	if err == nil {
+		err = os.MkdirAll(f.Dir, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(filename, rec, 0o644)
+	}
+	if err != nil {
+		log.Printf("Failed to record %s: %v", req.URL, err)
+	}
+	return resp, nil
+}
+
+func (f *ReplayFetcher) filename(targetURL string) string {
+	sum := sha256.Sum256([]byte(targetURL))
+	return filepath.Join(f.Dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"go-crawler/pkg/models"
+	"log"
+	"net/http"
+)
+
+// ValidatorStore looks up the validators saved with the previous crawl of a page.
+// Empty strings mean the page is new or the server sent none.
+type ValidatorStore interface {
+	LoadValidators(url string) (etag, lastModified string, err error)
+}
+
+// LinkStore looks up the links followed from a page on its previous crawl, so a
+// page that comes back 304 still feeds the worklist.
+type LinkStore interface {
+	LoadLinks(url string) ([]string, error)
+}
+
+// conditionalHeaders returns If-None-Match / If-Modified-Since for a page crawled
+// before, or nil when there is nothing to revalidate against.
+func (p *Parser) conditionalHeaders(targetURL string) http.Header {
+	if p.Validators == nil {
+		return nil
+	}
+	etag, lastModified, err := p.Validators.LoadValidators(targetURL)
+	if err != nil {
+		log.Printf("Failed to load validators for %s: %v", targetURL, err)
+		return nil
+	}
+	if etag == "" && lastModified == "" {
+		return nil
+	}
+
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+	return header
+}
+
+// revalidate asks the server whether a page is unchanged before paying for a
+// Chrome render. The body of any other answer is discarded unread, but its
+// headers are returned so the render can still be saved with its validators.
+func (p *Parser) revalidate(ctx context.Context, targetURL string, conditional http.Header) (models.PageData, http.Header, bool) {
+	resp, err := p.Static.Fetch(FetchRequest{URL: targetURL, Header: conditional, Ctx: ctx})
+	if err != nil {
+		return models.PageData{}, nil, false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		return models.PageData{}, resp.Header, false
+	}
+	return notModified(targetURL, resp.Header, conditional), resp.Header, true
+}
+
+// notModified is the result of a 304: only the crawl time and validators need
+// saving. Servers may omit validators on a 304, in which case the old ones stand.
+func notModified(targetURL string, header, conditional http.Header) models.PageData {
+	data := models.PageData{URL: targetURL, StatusCode: http.StatusNotModified, NotModified: true}
+	data.ETag, data.LastModified = validators(header)
+	if data.ETag == "" {
+		data.ETag = conditional.Get("If-None-Match")
+	}
+	if data.LastModified == "" {
+		data.LastModified = conditional.Get("If-Modified-Since")
+	}
+	return data
+}
+
+func validators(header http.Header) (etag, lastModified string) {
+	if header == nil {
+		return "", ""
+	}
+	return header.Get("ETag"), header.Get("Last-Modified")
+}
@@ -0,0 +1,117 @@
+package crawler
+
+import (
+	"context"
+	"go-crawler/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type staticValidators map[string][2]string
+
+func (v staticValidators) LoadValidators(url string) (string, string, error) {
+	return v[url][0], v[url][1], nil
+}
+
+func TestParser_ConditionalGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 05 Oct 2026 10:00:00 GMT")
+		w.Write([]byte(`<html><head><title>Hello</title></head><body>` + strings.Repeat("Some real page content. ", 20) + `</body></html>`))
+	}))
+	defer server.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	p.Validators = staticValidators{}
+
+	data, err := p.Parse(server.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.NotModified || data.ETag != `"v1"` || data.LastModified == "" {
+		t.Fatalf("Expected a full fetch that records validators, got %+v", data)
+	}
+
+	p.Validators = staticValidators{server.URL + "/page": {`"v1"`, data.LastModified}}
+	data, err = p.Parse(server.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.NotModified || data.StatusCode != http.StatusNotModified {
+		t.Fatalf("Expected a 304 to be reported as not modified, got %+v", data)
+	}
+	if data.ETag != `"v1"` {
+		t.Errorf("Expected the old ETag to be kept when the 304 omits it, got %q", data.ETag)
+	}
+}
+
+func TestParser_DynamicPageRevalidatesOnlyWithValidators(t *testing.T) {
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{})
+	p := NewParser("test", nil, d)
+	static := &fakeFetcher{body: "unused"}
+	dynamic := &fakeFetcher{body: `<html><head><title>Rendered</title></head><body></body></html>`}
+	p.Static, p.Dynamic = static, dynamic
+	p.Validators = staticValidators{}
+	d.MarkDynamic("https://spa.example/")
+
+	if _, err := p.Parse("https://spa.example/"); err != nil {
+		t.Fatal(err)
+	}
+	if static.calls != 0 || dynamic.calls != 1 {
+		t.Errorf("Expected a page without stored validators to be rendered straight away, got %d static / %d dynamic fetches", static.calls, dynamic.calls)
+	}
+
+	p.Validators = staticValidators{"https://spa.example/": {`"v1"`, ""}}
+	if _, err := p.Parse("https://spa.example/"); err != nil {
+		t.Fatal(err)
+	}
+	if static.calls != 1 || dynamic.calls != 2 {
+		t.Errorf("Expected a conditional GET before rendering a page with validators, got %d static / %d dynamic fetches", static.calls, dynamic.calls)
+	}
+}
+
+type staticLinks map[string][]string
+
+func (l staticLinks) LoadLinks(url string) ([]string, error) {
+	return l[url], nil
+}
+
+func TestPageProcessor_NotModifiedReemitsStoredLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	p := NewParser("test", nil, NewDomainManager(RateConfig{}, RobotsConfig{Agent: "test"}, HostStateConfig{}))
+	p.Validators = staticValidators{server.URL + "/hub": {`"v1"`, ""}}
+	processor := &PageProcessor{
+		Parser: p,
+		Filter: filterFunc(func(link string) bool { return !strings.Contains(link, "/excluded") }),
+		Links:  staticLinks{server.URL + "/hub": {server.URL + "/a", server.URL + "/excluded"}},
+	}
+
+	data, links, err := processor.Process(context.Background(), server.URL+"/hub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || !data[0].NotModified {
+		t.Fatalf("Expected the 304 page to be refreshed, got %+v", data)
+	}
+	if len(links) != 1 || links[0] != server.URL+"/a" {
+		t.Errorf("Expected the stored links that still pass the filter, got %v", links)
+	}
+}
+
+// filterFunc adapts a function to URLFilter in tests.
+type filterFunc func(link string) bool
+
+func (f filterFunc) Filter(source models.DataSource, link string) bool {
+	return f(link)
+}
@@ -0,0 +1,193 @@
+package crawler
+
+import (
+	"github.com/temoto/robotstxt"
+	"go-crawler/pkg/models"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RobotsConfig controls how robots.txt is fetched and cached (RFC 9309).
+type RobotsConfig struct {
+	Agent        string        // robots.txt token we obey
+	UserAgent    string        // Sent when fetching robots.txt
+	TTL          time.Duration // How long a fetched robots.txt is trusted (RFC 9309: no more than 24h)
+	ErrorTTL     time.Duration // How long a 5xx/unreachable "disallow all" lasts before refetching
+	MaxSize      int64         // Bytes of robots.txt that are parsed (RFC 9309: at least 500 KiB)
+	MaxRedirects int           // Redirect hops followed (RFC 9309: at least 5)
+	Store        RobotsStore   // Optional: persists fetched robots.txt across runs
+}
+
+// RobotsStore persists raw robots.txt responses so a restart doesn't refetch every host.
+type RobotsStore interface {
+	LoadRobots(origin string) (models.RobotsRecord, bool, error)
+	SaveRobots(record models.RobotsRecord) error
+}
+
+func (c RobotsConfig) withDefaults() RobotsConfig {
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+	}
+	if c.ErrorTTL <= 0 {
+		c.ErrorTTL = 10 * time.Minute
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = 500 * 1024
+	}
+	if c.MaxRedirects <= 0 {
+		c.MaxRedirects = 5
+	}
+	return c
+}
+
+// robotsEntry is the cached decision for one origin (scheme://host:port).
+type robotsEntry struct {
+	group       *robotstxt.Group // nil = no rules for us, allow all
+	disallowAll bool             // Server error or unreachable: stay away until it expires
+	sitemaps    []string         // "Sitemap:" lines
+	expires     time.Time
+}
+
+func (e *robotsEntry) allows(path string) bool {
+	if e.disallowAll {
+		return false
+	}
+	if e.group == nil {
+		return true
+	}
+	return e.group.Test(path)
+}
+
+func newRobotsClient(cfg RobotsConfig) *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.MaxRedirects {
+				// Hand back the 3xx; entryFromRecord treats it as "unavailable".
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// SetProxies routes robots.txt fetches through the proxy pool, so a host never
+// sees the crawler's own IP. Call it before crawling starts.
+func (d *DomainManager) SetProxies(proxies *ProxyPool) {
+	d.robotsClient.Transport = proxies.Transport()
+}
+
+// robotsFor returns the cached entry for the URL's origin, fetching it if it is
+// missing or expired. Concurrent callers for the same origin share one fetch.
+func (d *DomainManager) robotsFor(u *url.URL) *robotsEntry {
+	origin := u.Scheme + "://" + u.Host
+
+	entry, exists := d.robotsCache.Get(origin)
+	if exists && time.Now().Before(entry.expires) {
+		return entry
+	}
+
+	result, _, _ := d.robotsFlight.Do(origin, func() (interface{}, error) {
+		record := d.loadOrFetchRobots(origin)
+		entry := d.entryFromRecord(record)
+
+		d.mu.Lock()
+		d.robotsCache.Put(origin, entry)
+		d.applyCrawlDelay(u.Host, entry.group)
+		d.mu.Unlock()
+		return entry, nil
+	})
+	return result.(*robotsEntry)
+}
+
+// Sitemaps returns the sitemaps advertised in the origin's robots.txt,
+// or the conventional /sitemap.xml if there are none.
+func (d *DomainManager) Sitemaps(link string) []string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil
+	}
+	if sitemaps := d.robotsFor(u).sitemaps; len(sitemaps) > 0 {
+		return sitemaps
+	}
+	return []string{u.Scheme + "://" + u.Host + "/sitemap.xml"}
+}
+
+func (d *DomainManager) loadOrFetchRobots(origin string) models.RobotsRecord {
+	if d.robots.Store != nil {
+		record, found, err := d.robots.Store.LoadRobots(origin)
+		if err != nil {
+			log.Printf("Failed to load robots.txt for %s: %v", origin, err)
+		} else if found && time.Since(record.FetchedAt) < d.robots.TTL {
+			return record
+		}
+	}
+
+	record := d.fetchRobots(origin)
+
+	// Server errors are only remembered for ErrorTTL, so there's no point persisting them.
+	if d.robots.Store != nil && !isRobotsServerError(record.StatusCode) {
+		if err := d.robots.Store.SaveRobots(record); err != nil {
+			log.Printf("Failed to save robots.txt for %s: %v", origin, err)
+		}
+	}
+	return record
+}
+
+func (d *DomainManager) fetchRobots(origin string) models.RobotsRecord {
+	record := models.RobotsRecord{Origin: origin, FetchedAt: time.Now()}
+
+	req, err := http.NewRequest("GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return record
+	}
+	req.Header.Set("User-Agent", d.robots.UserAgent)
+
+	resp, err := d.robotsClient.Do(req)
+	if err != nil {
+		// StatusCode 0 = unreachable
+		return record
+	}
+	defer resp.Body.Close()
+
+	record.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		// Anything past MaxSize is ignored, as RFC 9309 allows.
+		body, err := io.ReadAll(io.LimitReader(resp.Body, d.robots.MaxSize))
+		if err != nil {
+			record.StatusCode = 0
+			return record
+		}
+		record.Body = body
+	}
+	return record
+}
+
+// entryFromRecord applies the RFC 9309 status rules:
+// 2xx parse, 4xx (and too many redirects) allow all, 5xx/unreachable disallow all for a while.
+func (d *DomainManager) entryFromRecord(record models.RobotsRecord) *robotsEntry {
+	if isRobotsServerError(record.StatusCode) {
+		return &robotsEntry{disallowAll: true, expires: record.FetchedAt.Add(d.robots.ErrorTTL)}
+	}
+
+	entry := &robotsEntry{expires: record.FetchedAt.Add(d.robots.TTL)}
+	if record.StatusCode >= 200 && record.StatusCode < 300 {
+		data, err := robotstxt.FromBytes(record.Body)
+		if err != nil {
+			log.Printf("Unparseable robots.txt at %s: %v", record.Origin, err)
+			return entry
+		}
+		entry.group = data.FindGroup(d.robots.Agent)
+		entry.sitemaps = data.Sitemaps
+	}
+	return entry
+}
+
+// isRobotsServerError covers 5xx, unreachable (0) and 429, which RFC 9309 crawlers
+// commonly treat like a server error rather than "not found".
+func isRobotsServerError(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDomainManager_RobotsStatusRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		path     string
+		expected bool
+	}{
+		{"parsed rules", 200, "User-agent: testbot\nDisallow: /private\n", "/private/page", false},
+		{"other agents ignored", 200, "User-agent: otherbot\nDisallow: /\n", "/page", true},
+		{"4xx allows all", 404, "", "/private/page", true},
+		{"5xx disallows all", 503, "", "/page", false},
+		{"429 disallows all", 429, "", "/page", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "testbot"}, HostStateConfig{})
+			if got := d.IsAllowed(server.URL + tt.path); got != tt.expected {
+				t.Errorf("IsAllowed(%s) = %v, expected %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDomainManager_RobotsSingleFlightAndTTL(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "testbot", TTL: 50 * time.Millisecond}, HostStateConfig{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.IsAllowed(server.URL + "/page")
+		}()
+	}
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("Expected concurrent lookups to share 1 fetch, got %d", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	d.IsAllowed(server.URL + "/page")
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("Expected a refetch after the TTL expired, got %d fetches", n)
+	}
+}
+
+func TestDomainManager_RobotsRedirectLimit(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// An endless redirect loop counts as "unavailable", which allows everything.
+		http.Redirect(w, r, server.URL+"/robots.txt?loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "testbot", MaxRedirects: 2}, HostStateConfig{})
+	if !d.IsAllowed(server.URL + "/page") {
+		t.Error("Expected too many redirects to be treated as allow-all")
+	}
+}
@@ -1,6 +1,7 @@
 package crawler
 
 import (
+	"context"
 	"go-crawler/pkg/models"
 )
 
@@ -9,7 +10,7 @@ type ScoutProcessor struct {
 	Filter URLFilter
 }
 
-func (s *ScoutProcessor) Process(url string) ([]models.URLQueue, []string, error) {
+func (s *ScoutProcessor) Process(ctx context.Context, url string) ([]models.URLQueue, []string, error) {
 	// 1. FetchStatic and extract links
 	allLinks, err := s.Parser.GetOutBoundLinks(url)
 	if err != nil {
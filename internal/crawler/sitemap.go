@@ -0,0 +1,253 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"go-crawler/pkg/models"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SitemapSink persists discovered sitemap entries (the ground truth for coverage reports).
+type SitemapSink interface {
+	Save(batch []models.SitemapEntry) error
+}
+
+// SitemapDiscoverer finds a host's sitemaps (robots.txt "Sitemap:" lines, falling back
+// to /sitemap.xml), parses them and returns their URLs best-first for the frontier.
+type SitemapDiscoverer struct {
+	UserAgent string
+	MaxURLs   int         // Per host (0 = unlimited)
+	Filter    URLFilter   // Optional: same scope rules as followed links
+	Sink      SitemapSink // Optional
+
+	domainMgr *DomainManager
+	client    *http.Client
+}
+
+const (
+	sitemapMaxDepth = 3                // sitemapindex -> sitemapindex -> urlset is plenty
+	sitemapMaxBytes = 50 * 1024 * 1024 // Protocol limit for an uncompressed sitemap
+)
+
+func NewSitemapDiscoverer(userAgent string, maxURLs int, domainMgr *DomainManager) *SitemapDiscoverer {
+	return &SitemapDiscoverer{
+		UserAgent: userAgent,
+		MaxURLs:   maxURLs,
+		domainMgr: domainMgr,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetProxies routes sitemap fetches through the proxy pool. Call it before crawling starts.
+func (s *SitemapDiscoverer) SetProxies(proxies *ProxyPool) {
+	s.client.Transport = proxies.Transport()
+}
+
+// Discover returns the sitemap entries for the link's origin, highest priority
+// (then most recently modified) first.
+func (s *SitemapDiscoverer) Discover(ctx context.Context, link string) []models.SitemapEntry {
+	var entries []models.SitemapEntry
+	seen := make(map[string]bool)
+
+	for _, sitemapURL := range s.domainMgr.Sitemaps(link) {
+		s.walk(ctx, link, sitemapURL, 0, seen, &entries)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Priority != entries[j].Priority {
+			return entries[i].Priority > entries[j].Priority
+		}
+		return entries[i].LastMod.After(entries[j].LastMod)
+	})
+
+	if s.Sink != nil && len(entries) > 0 {
+		if err := s.Sink.Save(entries); err != nil {
+			log.Printf("Failed to save sitemap entries for %s: %v", link, err)
+		}
+	}
+	return entries
+}
+
+// walk reads sitemapURL, which origin's robots.txt declared (or a sitemap index it
+// declared lists). Only entries on origin's host are kept.
+func (s *SitemapDiscoverer) walk(ctx context.Context, origin, sitemapURL string, depth int, seen map[string]bool, entries *[]models.SitemapEntry) {
+	if depth > sitemapMaxDepth || seen[sitemapURL] || s.full(*entries) {
+		return
+	}
+	seen[sitemapURL] = true
+
+	if !s.domainMgr.IsAllowed(sitemapURL) {
+		return
+	}
+	if err := s.domainMgr.Wait(ctx, sitemapURL); err != nil {
+		log.Println(err)
+		return
+	}
+
+	doc, err := s.fetch(sitemapURL)
+	if err != nil {
+		log.Printf("[Sitemap] %s: %v", sitemapURL, err)
+		return
+	}
+
+	for _, child := range doc.Sitemaps {
+		s.walk(ctx, origin, strings.TrimSpace(child.Loc), depth+1, seen, entries)
+	}
+
+	for _, u := range doc.URLs {
+		if s.full(*entries) {
+			log.Printf("[Sitemap] Reached %d URLs for %s, ignoring the rest", s.MaxURLs, sitemapURL)
+			return
+		}
+		loc := strings.TrimSpace(u.Loc)
+		if !sameHost(origin, loc) {
+			// A sitemap may only list URLs of the host that declared it; robots.txt
+			// may point at one hosted elsewhere (e.g. a CDN).
+			continue
+		}
+		if s.Filter != nil && !s.Filter.Filter(models.None, loc) {
+			continue
+		}
+		*entries = append(*entries, models.SitemapEntry{
+			URL:      loc,
+			Sitemap:  sitemapURL,
+			LastMod:  parseLastMod(u.LastMod),
+			Priority: parsePriority(u.Priority),
+		})
+	}
+}
+
+func (s *SitemapDiscoverer) full(entries []models.SitemapEntry) bool {
+	return s.MaxURLs > 0 && len(entries) >= s.MaxURLs
+}
+
+// sitemapDoc holds the entries of both <urlset> and <sitemapindex>.
+type sitemapDoc struct {
+	URLs     []sitemapURL
+	Sitemaps []sitemapRef
+}
+
+type sitemapURL struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod"`
+	Priority string `xml:"priority"`
+}
+
+type sitemapRef struct {
+	Loc string `xml:"loc"`
+}
+
+func (s *SitemapDiscoverer) fetch(sitemapURL string) (*sitemapDoc, error) {
+	req, err := http.NewRequest("GET", sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.UserAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	// Sitemaps are often served as .xml.gz with a generic content type, so sniff the gzip magic bytes.
+	var body io.Reader = bufio.NewReader(resp.Body)
+	if magic, _ := body.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	doc, err := decodeSitemap(io.LimitReader(body, sitemapMaxBytes))
+	if err != nil {
+		if len(doc.URLs) == 0 && len(doc.Sitemaps) == 0 {
+			return nil, fmt.Errorf("invalid sitemap: %w", err)
+		}
+		// Cut at sitemapMaxBytes, or broken halfway: keep what came before.
+		log.Printf("[Sitemap] %s: keeping %d URLs and %d sitemaps read before: %v", sitemapURL, len(doc.URLs), len(doc.Sitemaps), err)
+	}
+	return doc, nil
+}
+
+// decodeSitemap reads <url> and <sitemap> elements one at a time, so on error the
+// entries decoded up to that point are still returned.
+func decodeSitemap(r io.Reader) (*sitemapDoc, error) {
+	doc := &sitemapDoc{}
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return doc, nil
+		}
+		if err != nil {
+			return doc, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "url":
+			var u sitemapURL
+			if err := dec.DecodeElement(&u, &start); err != nil {
+				return doc, err
+			}
+			doc.URLs = append(doc.URLs, u)
+		case "sitemap":
+			var ref sitemapRef
+			if err := dec.DecodeElement(&ref, &start); err != nil {
+				return doc, err
+			}
+			doc.Sitemaps = append(doc.Sitemaps, ref)
+		}
+	}
+}
+
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host)
+}
+
+// parseLastMod accepts the W3C Datetime formats used by sitemaps. Unknown = zero time.
+func parseLastMod(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parsePriority defaults to 0.5 as the protocol specifies.
+func parsePriority(s string) float64 {
+	p, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || p < 0 || p > 1 {
+		return 0.5
+	}
+	return p
+}
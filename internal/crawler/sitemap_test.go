@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSitemapDiscoverer_IndexAndGzip(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /private\nSitemap: %s/sitemap_index.xml\n", server.URL)
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<?xml version="1.0"?>
+				<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+					<sitemap><loc>%s/pages.xml.gz</loc></sitemap>
+				</sitemapindex>`, server.URL)
+		case "/pages.xml.gz":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			fmt.Fprintf(gz, `<?xml version="1.0"?>
+				<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+					<url><loc>%[1]s/old</loc><lastmod>2020-01-01</lastmod></url>
+					<url><loc>%[1]s/new</loc><lastmod>2024-05-01T10:00:00+00:00</lastmod></url>
+					<url><loc>%[1]s/home</loc><priority>1.0</priority></url>
+					<url><loc>https://elsewhere.example/page</loc></url>
+				</urlset>`, server.URL)
+			gz.Close()
+			w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "testbot"}, HostStateConfig{})
+	entries := NewSitemapDiscoverer("testbot", 0, d).Discover(context.Background(), server.URL+"/")
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.URL[len(server.URL):])
+	}
+	expected := []string{"/home", "/new", "/old"}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected entries %v (priority, then lastmod), got %v", expected, got)
+	}
+	if entries[1].LastMod.Year() != 2024 {
+		t.Errorf("Expected lastmod to be parsed, got %v", entries[1].LastMod)
+	}
+}
+
+func TestDomainManager_SitemapsDefault(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "testbot"}, HostStateConfig{})
+	sitemaps := d.Sitemaps(server.URL + "/page")
+	if len(sitemaps) != 1 || sitemaps[0] != server.URL+"/sitemap.xml" {
+		t.Errorf("Expected default /sitemap.xml, got %v", sitemaps)
+	}
+}
+
+func TestDecodeSitemap_KeepsEntriesBeforeTheCut(t *testing.T) {
+	full := `<urlset><url><loc>https://a.example/1</loc></url><url><loc>https://a.example/2</loc></url><url><loc>https://a.ex`
+	doc, err := decodeSitemap(strings.NewReader(full))
+	if err == nil {
+		t.Error("Expected an error for a truncated sitemap")
+	}
+	if len(doc.URLs) != 2 || doc.URLs[1].Loc != "https://a.example/2" {
+		t.Errorf("Expected the 2 complete entries to be kept, got %+v", doc.URLs)
+	}
+}
+
+func TestSitemapDiscoverer_SitemapOnAnotherHost(t *testing.T) {
+	var site *httptest.Server
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sitemap.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<?xml version="1.0"?>
+			<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+				<url><loc>%s/page</loc></url>
+				<url><loc>http://%s/asset</loc></url>
+			</urlset>`, site.URL, r.Host)
+	}))
+	defer cdn.Close()
+	site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprintf(w, "User-agent: *\nSitemap: %s/sitemap.xml\n", cdn.URL)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer site.Close()
+
+	d := NewDomainManager(RateConfig{}, RobotsConfig{Agent: "testbot"}, HostStateConfig{})
+	entries := NewSitemapDiscoverer("testbot", 0, d).Discover(context.Background(), site.URL+"/")
+	if len(entries) != 1 || entries[0].URL != site.URL+"/page" {
+		t.Errorf("Expected only the declaring host's entry from the CDN sitemap, got %v", entries)
+	}
+}
@@ -0,0 +1,312 @@
+package crawler
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TabPoolConfig sizes the pool of warm Chrome tabs renders are done in.
+type TabPoolConfig struct {
+	Size    int // Tabs rendering at once; further renders wait for a free one (0 = 4)
+	MaxUses int // Pages a tab renders before it is replaced by a fresh one (0 = 50)
+}
+
+func (c TabPoolConfig) withDefaults() TabPoolConfig {
+	if c.Size <= 0 {
+		c.Size = 4
+	}
+	if c.MaxUses <= 0 {
+		c.MaxUses = 50
+	}
+	return c
+}
+
+// chromeTab is a tab in its own BrowserContext, so cookies and storage never
+// leak between tabs. Between renders it is reset rather than closed.
+type chromeTab struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	browser context.Context // Of the browser the tab was opened in
+	proxy   *url.URL        // A BrowserContext is bound to one proxy (nil = direct)
+	uses    int
+	origins map[string]bool // Visited since the last reset, to clear their storage
+
+	guardMu sync.Mutex
+	guard   func(link string) error // Vets main-frame navigations of the current render (nil = all pass)
+}
+
+// visit notes the origin of link, so reset clears what it stored.
+func (t *chromeTab) visit(link string) {
+	if u, err := url.Parse(link); err == nil && u.Host != "" {
+		t.origins[u.Scheme+"://"+u.Host] = true
+	}
+}
+
+// setGuard makes guard vet the tab's main-frame navigations until it is replaced.
+func (t *chromeTab) setGuard(guard func(link string) error) {
+	t.guardMu.Lock()
+	defer t.guardMu.Unlock()
+	t.guard = guard
+}
+
+// intercept pauses the tab's document requests, so a navigation the render's
+// guard refuses is failed before it is sent, and answers the proxy's auth
+// challenge. Run the returned action once, when the tab is set up.
+func (t *chromeTab) intercept(ctx context.Context) chromedp.Action {
+	auth := chromeProxyAuth(t.proxy)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		// Handlers must not block the event loop, hence the goroutines.
+		switch e := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() { chromedp.Run(ctx, t.vet(ctx, e)) }()
+		case *fetch.EventAuthRequired:
+			if auth != nil {
+				go chromedp.Run(ctx, fetch.ContinueWithAuth(e.RequestID, auth))
+			}
+		}
+	})
+	pattern := &fetch.RequestPattern{URLPattern: "*", ResourceType: network.ResourceTypeDocument}
+	if auth != nil {
+		// Auth challenges are only raised for paused requests, so pause them all.
+		pattern = &fetch.RequestPattern{URLPattern: "*"}
+	}
+	return fetch.Enable().WithPatterns([]*fetch.RequestPattern{pattern}).WithHandleAuthRequests(auth != nil)
+}
+
+// vet continues a paused request, or fails it if it navigates the main frame
+// somewhere the guard refuses. The main frame's ID is the tab's target ID.
+func (t *chromeTab) vet(ctx context.Context, e *fetch.EventRequestPaused) chromedp.Action {
+	t.guardMu.Lock()
+	guard := t.guard
+	t.guardMu.Unlock()
+
+	mainFrame := e.ResourceType == network.ResourceTypeDocument &&
+		string(e.FrameID) == string(chromedp.FromContext(ctx).Target.TargetID)
+	if guard != nil && mainFrame && guard(e.Request.URL) != nil {
+		return fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient)
+	}
+	return fetch.ContinueRequest(e.RequestID)
+}
+
+// TabPool keeps one browser process and up to Size tabs warm across renders.
+// The browser is started on first use and restarted if it dies.
+type TabPool struct {
+	cfg      TabPoolConfig
+	allocCtx context.Context
+	slots    chan struct{} // One token per tab in use
+
+	mu            sync.Mutex
+	browserCtx    context.Context
+	cancelBrowser context.CancelFunc
+	idle          []*chromeTab
+	open          int
+}
+
+func NewTabPool(allocCtx context.Context, cfg TabPoolConfig) *TabPool {
+	cfg = cfg.withDefaults()
+	return &TabPool{cfg: cfg, allocCtx: allocCtx, slots: make(chan struct{}, cfg.Size)}
+}
+
+// acquire waits for a free slot and returns an idle tab going through proxy,
+// opening a new one if there is none.
+func (tp *TabPool) acquire(proxy *url.URL) (*chromeTab, error) {
+	tp.slots <- struct{}{}
+
+	tp.mu.Lock()
+	tp.dropDeadTabs()
+	for i, t := range tp.idle {
+		if sameProxy(t.proxy, proxy) {
+			tp.idle = append(tp.idle[:i], tp.idle[i+1:]...)
+			tp.mu.Unlock()
+			return t, nil
+		}
+	}
+	// Make room by closing an idle tab bound to another proxy.
+	if tp.open >= tp.cfg.Size && len(tp.idle) > 0 {
+		tp.closeTab(tp.idle[0])
+		tp.idle = tp.idle[1:]
+	}
+	tp.open++
+	tp.mu.Unlock()
+
+	t, err := tp.newTab(proxy)
+	if err != nil {
+		tp.mu.Lock()
+		tp.open--
+		tp.mu.Unlock()
+		<-tp.slots
+		return nil, err
+	}
+	return t, nil
+}
+
+// release hands a tab back after a render. A tab that failed, is worn out or
+// can't be reset is closed instead; the next acquire opens a fresh one.
+func (tp *TabPool) release(t *chromeTab, healthy bool) {
+	defer func() { <-tp.slots }()
+
+	t.uses++
+	if !healthy || t.uses >= tp.cfg.MaxUses || tp.reset(t) != nil {
+		tp.mu.Lock()
+		tp.closeTab(t)
+		tp.mu.Unlock()
+		return
+	}
+	tp.mu.Lock()
+	tp.idle = append(tp.idle, t)
+	tp.mu.Unlock()
+}
+
+// Close shuts down every idle tab and the browser. Tabs in use are closed with it.
+func (tp *TabPool) Close() {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	for _, t := range tp.idle {
+		tp.closeTab(t)
+	}
+	tp.idle = nil
+	if tp.cancelBrowser != nil {
+		tp.cancelBrowser()
+		tp.browserCtx, tp.cancelBrowser = nil, nil
+	}
+}
+
+// closeTab closes the tab and disposes of its BrowserContext. Callers must hold tp.mu.
+func (tp *TabPool) closeTab(t *chromeTab) {
+	t.cancel()
+	tp.open--
+}
+
+// dropDeadTabs closes the idle tabs of a browser that has been replaced. Callers
+// must hold tp.mu.
+func (tp *TabPool) dropDeadTabs() {
+	live := tp.idle[:0]
+	for _, t := range tp.idle {
+		if t.browser.Err() != nil || t.ctx.Err() != nil {
+			tp.closeTab(t)
+			continue
+		}
+		live = append(live, t)
+	}
+	tp.idle = live
+}
+
+func (tp *TabPool) newTab(proxy *url.URL) (*chromeTab, error) {
+	browserCtx, err := tp.browser()
+	if err != nil {
+		return nil, err
+	}
+	t, err := tp.openTab(browserCtx, proxy)
+	if err == nil || browserAlive(browserCtx) {
+		// Other renders are still using the browser: only this tab failed.
+		return t, err
+	}
+
+	// The browser crashed: start a new one and try once more.
+	log.Printf("[Chrome] Failed to open a tab (%v) and the browser is gone, restarting it", err)
+	tp.mu.Lock()
+	if tp.browserCtx == browserCtx {
+		tp.cancelBrowser()
+		tp.browserCtx, tp.cancelBrowser = nil, nil
+	}
+	tp.dropDeadTabs()
+	tp.mu.Unlock()
+	if browserCtx, err = tp.browser(); err != nil {
+		return nil, err
+	}
+	return tp.openTab(browserCtx, proxy)
+}
+
+// browserAlive reports whether the browser behind ctx still answers.
+func browserAlive(ctx context.Context) bool {
+	c := chromedp.FromContext(ctx)
+	if ctx.Err() != nil || c == nil || c.Browser == nil {
+		return false
+	}
+	probe, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, _, _, _, _, err := browser.GetVersion().Do(cdp.WithExecutor(probe, c.Browser))
+	return err == nil
+}
+
+func (tp *TabPool) openTab(browserCtx context.Context, proxy *url.URL) (*chromeTab, error) {
+	ctx, cancel := chromedp.NewContext(browserCtx, chromedp.WithNewBrowserContext(
+		func(params *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			if proxy != nil {
+				return params.WithProxyServer(chromeProxyServer(proxy))
+			}
+			return params
+		},
+	))
+	t := &chromeTab{ctx: ctx, cancel: cancel, browser: browserCtx, proxy: proxy, origins: make(map[string]bool)}
+	err := chromedp.Run(ctx,
+		t.intercept(ctx),
+		// Removes the "I am a robot" flag on every page the tab loads
+		chromedp.ActionFunc(func(c context.Context) error {
+			_, err := page.AddScriptToEvaluateOnNewDocument(scriptStealth).Do(c)
+			return err
+		}),
+		network.Enable(),
+	)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return t, nil
+}
+
+// browser returns the context of the running browser, starting it if needed.
+func (tp *TabPool) browser() (context.Context, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if tp.browserCtx != nil && tp.browserCtx.Err() == nil {
+		return tp.browserCtx, nil
+	}
+
+	ctx, cancel := chromedp.NewContext(tp.allocCtx,
+		chromedp.WithLogf(func(string, ...interface{}) {}),
+	)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+	tp.browserCtx, tp.cancelBrowser = ctx, cancel
+	return ctx, nil
+}
+
+// reset wipes what the last render left behind: cookies, the storage of every
+// origin it visited, and the page itself.
+func (tp *TabPool) reset(t *chromeTab) error {
+	ctx, cancel := context.WithTimeout(t.ctx, 5*time.Second)
+	defer cancel()
+
+	actions := []chromedp.Action{network.ClearBrowserCookies()}
+	for origin := range t.origins {
+		actions = append(actions, storage.ClearDataForOrigin(origin, "all"))
+	}
+	actions = append(actions, chromedp.Navigate("about:blank"))
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return err
+	}
+	clear(t.origins)
+	return nil
+}
+
+func sameProxy(a, b *url.URL) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return strings.EqualFold(a.String(), b.String())
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"context"
+	"github.com/chromedp/chromedp"
+	"testing"
+	"time"
+)
+
+func TestTabPool_FailedLaunchFreesSlot(t *testing.T) {
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), chromedp.ExecPath("/nonexistent/chrome"))
+	defer cancel()
+	pool := NewTabPool(allocCtx, TabPoolConfig{Size: 1})
+	defer pool.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 3 {
+			if _, err := pool.acquire(nil); err == nil {
+				t.Error("Expected acquire to fail without a browser")
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("acquire blocked: a failed launch kept its slot")
+	}
+	if pool.open != 0 {
+		t.Errorf("Expected no open tabs, got %d", pool.open)
+	}
+}
+
+func TestTabPool_DropsIdleTabsOfADeadBrowser(t *testing.T) {
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), chromedp.ExecPath("/nonexistent/chrome"))
+	defer cancel()
+	pool := NewTabPool(allocCtx, TabPoolConfig{Size: 2})
+	defer pool.Close()
+
+	deadBrowser, kill := context.WithCancel(context.Background())
+	kill()
+	tabCtx, closeTab := context.WithCancel(context.Background())
+	defer closeTab()
+	pool.idle = []*chromeTab{{ctx: tabCtx, cancel: closeTab, browser: deadBrowser, origins: make(map[string]bool)}}
+	pool.open = 1
+
+	if tab, err := pool.acquire(nil); err == nil {
+		t.Fatalf("Expected the dead browser's tab to be dropped, got %v", tab)
+	}
+	if pool.open != 0 || len(pool.idle) != 0 || tabCtx.Err() == nil {
+		t.Errorf("Expected the tab closed and its slot freed, got %d open, %d idle", pool.open, len(pool.idle))
+	}
+}
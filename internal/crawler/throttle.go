@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"golang.org/x/time/rate"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateConfig controls per-host politeness. Delays are the gap between two requests
+// to the same host, so a smaller delay means a faster rate.
+type RateConfig struct {
+	Delay         time.Duration // Starting delay (RATE_LIMIT)
+	MinDelay      time.Duration // Ceiling on speed: never go faster than this (0 = Delay)
+	MaxDelay      time.Duration // Floor on speed: never back off slower than this
+	LatencyTarget time.Duration // Responses slower than this don't count as healthy
+	SpeedUpAfter  int           // Consecutive healthy responses before speeding up
+	MaxInFlight   int           // Concurrent requests per host (0 = unlimited)
+
+	Grouping    GroupMode     // Also pace hosts that share an IP or subnet together
+	GroupDelay  time.Duration // Gap between requests to one group
+	DNSCacheTTL time.Duration // How long a host -> group answer is cached
+}
+
+func (c RateConfig) withDefaults() RateConfig {
+	if c.MinDelay <= 0 || c.MinDelay > c.Delay {
+		c.MinDelay = c.Delay
+	}
+	if c.MaxDelay < c.Delay {
+		c.MaxDelay = max(c.Delay, 2*time.Minute)
+	}
+	if c.LatencyTarget <= 0 {
+		c.LatencyTarget = 3 * time.Second
+	}
+	if c.SpeedUpAfter <= 0 {
+		c.SpeedUpAfter = 10
+	}
+	if c.DNSCacheTTL <= 0 {
+		c.DNSCacheTTL = 10 * time.Minute
+	}
+	return c
+}
+
+// hostThrottle is the adaptive (AIMD) limiter for a single host: it backs off
+// multiplicatively on 429/503 and speeds up in small steps while the host is healthy.
+type hostThrottle struct {
+	limiter     *rate.Limiter
+	delay       time.Duration // Current gap between requests
+	minDelay    time.Duration // Fastest allowed (RATE_MIN_DELAY, or a stricter Crawl-delay)
+	pausedUntil time.Time     // Set from Retry-After
+	healthy     int           // Consecutive healthy responses since the last change
+	breaker     hostBreaker
+	restored    bool // Loaded from the HostStateStore
+}
+
+func newHostThrottle(cfg RateConfig) *hostThrottle {
+	return &hostThrottle{
+		// 1 = burst size (allow 1 request immediately, then wait)
+		limiter:  rate.NewLimiter(rate.Every(cfg.Delay), 1),
+		delay:    cfg.Delay,
+		minDelay: cfg.MinDelay,
+	}
+}
+
+func (t *hostThrottle) setDelay(delay time.Duration) {
+	t.delay = delay
+	t.limiter.SetLimit(rate.Every(delay))
+}
+
+// setFloor raises the fastest allowed rate's delay, e.g. for a robots.txt Crawl-delay.
+func (t *hostThrottle) setFloor(minDelay time.Duration) {
+	t.minDelay = max(t.minDelay, minDelay)
+	if t.delay < t.minDelay {
+		t.setDelay(t.minDelay)
+	}
+}
+
+// record updates the throttle with one fetch outcome. statusCode 0 = network error.
+func (t *hostThrottle) record(cfg RateConfig, statusCode int, latency time.Duration, retryAfter time.Duration) {
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		t.healthy = 0
+		t.setDelay(min(max(t.delay*2, time.Second), cfg.MaxDelay))
+		if retryAfter > 0 {
+			t.pausedUntil = time.Now().Add(min(retryAfter, cfg.MaxDelay))
+		}
+
+	case statusCode == 0 || statusCode >= 500 || latency > cfg.LatencyTarget:
+		// Unhealthy but not an explicit "slow down": just stop speeding up.
+		t.healthy = 0
+
+	default:
+		t.healthy++
+		if t.healthy >= cfg.SpeedUpAfter && t.delay > t.minDelay {
+			t.healthy = 0
+			// Additive increase in rate = shave 10% off the delay.
+			t.setDelay(max(t.delay-t.delay/10, t.minDelay))
+		}
+	}
+}
+
+// parseRetryAfter reads a Retry-After header in either seconds or HTTP-date form.
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHostThrottle_AIMD(t *testing.T) {
+	cfg := RateConfig{Delay: 2 * time.Second, MinDelay: time.Second, MaxDelay: 10 * time.Second}.withDefaults()
+	throttle := newHostThrottle(cfg)
+
+	throttle.record(cfg, http.StatusTooManyRequests, 0, 30*time.Second)
+	if throttle.delay != 4*time.Second {
+		t.Errorf("Expected delay to double on 429, got %s", throttle.delay)
+	}
+	if time.Until(throttle.pausedUntil) < 9*time.Second {
+		t.Errorf("Expected Retry-After pause (capped at MaxDelay), got %s", time.Until(throttle.pausedUntil))
+	}
+
+	for i := 0; i < 3; i++ {
+		throttle.record(cfg, http.StatusServiceUnavailable, 0, 0)
+	}
+	if throttle.delay != cfg.MaxDelay {
+		t.Errorf("Expected delay capped at %s, got %s", cfg.MaxDelay, throttle.delay)
+	}
+
+	// Slow responses don't count towards speeding up.
+	for i := 0; i < cfg.SpeedUpAfter; i++ {
+		throttle.record(cfg, http.StatusOK, 5*time.Second, 0)
+	}
+	if throttle.delay != cfg.MaxDelay {
+		t.Errorf("Expected no speed-up while latency is unhealthy, got %s", throttle.delay)
+	}
+
+	for i := 0; i < 1000; i++ {
+		throttle.record(cfg, http.StatusOK, 10*time.Millisecond, 0)
+	}
+	if throttle.delay != cfg.MinDelay {
+		t.Errorf("Expected healthy host to speed up to the ceiling %s, got %s", cfg.MinDelay, throttle.delay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "120")
+	if got := parseRetryAfter(header); got != 2*time.Minute {
+		t.Errorf("Expected 2m, got %s", got)
+	}
+
+	header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if got := parseRetryAfter(header); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("Expected about 1h from an HTTP-date, got %s", got)
+	}
+}
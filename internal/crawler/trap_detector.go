@@ -0,0 +1,233 @@
+package crawler
+
+import (
+	"fmt"
+	"go-crawler/internal"
+	"go-crawler/pkg/models"
+	"hash/fnv"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SuppressionReporter receives every URL the crawler decides not to queue or fetch,
+// together with the reason, so that suppressions can be reviewed after a crawl.
+type SuppressionReporter interface {
+	Report(s models.Suppression)
+}
+
+// LogReporter is the default SuppressionReporter. It just writes to the log.
+type LogReporter struct{}
+
+func (LogReporter) Report(s models.Suppression) {
+	log.Printf("[Suppressed] %s (%s): %s", s.URL, s.Source, s.Reason)
+}
+
+// TrapConfig holds the thresholds used by the TrapDetector.
+// A zero value for any limit disables that heuristic.
+type TrapConfig struct {
+	MaxPathDepth      int           // Maximum number of path segments
+	MaxSegmentRepeats int           // Maximum times a single segment may appear in one path
+	MaxQueryVariants  int           // Maximum distinct query strings per path template
+	MaxURLLength      int           // Maximum length of the full URL
+	MaxHostGrowth     int           // Maximum new URLs per host within GrowthWindow
+	GrowthWindow      time.Duration // Window used for MaxHostGrowth
+	MaxHosts          int           // Hosts tracked at once; the least recently seen are forgotten (0 = unbounded)
+	IdleTTL           time.Duration // Hosts not seen for this long are forgotten (0 = never)
+}
+
+// Per-host bounds, so a single huge host can't grow the detector without limit either.
+const (
+	maxSeenPerHost      = 50000 // URL hashes remembered; the set starts over once full
+	maxTemplatesPerHost = 1000  // Path templates whose query variants are counted, least recently used dropped
+)
+
+// TrapDetector spots URLs that belong to infinite URL spaces (calendars, faceted search,
+// session IDs, repeating paths) before they reach the worklist.
+type TrapDetector struct {
+	config   TrapConfig
+	reporter SuppressionReporter
+
+	mu    sync.Mutex
+	hosts *internal.LRU[string, *hostSpace]
+}
+
+// hostSpace tracks how the URL space of a single host is growing.
+type hostSpace struct {
+	seen        map[uint64]struct{}                        // Hashes of URLs seen on this host
+	variants    *internal.LRU[string, map[uint64]struct{}] // Path template -> hashes of distinct query strings
+	windowStart time.Time
+	windowCount int
+}
+
+var (
+	numericSegment = regexp.MustCompile(`^\d+$`)
+	// Long hex/base64-ish tokens are usually IDs or session tokens, not real directories.
+	tokenSegment   = regexp.MustCompile(`^[A-Za-z0-9_-]{16,}$`)
+	sessionSegment = regexp.MustCompile(`(?i)(^|[/;&])(jsessionid|phpsessid|sessionid|sid|aspsessionid[a-z]*)=`)
+)
+
+func NewTrapDetector(cfg TrapConfig, reporter SuppressionReporter) *TrapDetector {
+	if reporter == nil {
+		reporter = LogReporter{}
+	}
+	if cfg.GrowthWindow <= 0 {
+		cfg.GrowthWindow = time.Minute
+	}
+	return &TrapDetector{
+		config:   cfg,
+		reporter: reporter,
+		hosts:    internal.NewLRU[string, *hostSpace](cfg.MaxHosts, cfg.IdleTTL, nil),
+	}
+}
+
+// Filter returns only the links that do not look like a crawler trap.
+// Every link that is dropped is sent to the reporter.
+func (t *TrapDetector) Filter(links []string) []string {
+	kept := links[:0:0]
+	for _, link := range links {
+		if reason := t.Check(link); reason != "" {
+			t.reporter.Report(models.Suppression{
+				URL:    link,
+				Source: "trap",
+				Reason: reason,
+				At:     time.Now(),
+			})
+			continue
+		}
+		kept = append(kept, link)
+	}
+	return kept
+}
+
+// Check returns a non-empty reason if the link looks like a crawler trap.
+func (t *TrapDetector) Check(link string) string {
+	if t.config.MaxURLLength > 0 && len(link) > t.config.MaxURLLength {
+		return fmt.Sprintf("url length %d exceeds %d", len(link), t.config.MaxURLLength)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return "unparseable url"
+	}
+
+	if sessionSegment.MatchString(u.Path) {
+		return "session id in path"
+	}
+
+	segments := splitPath(u.Path)
+	if t.config.MaxPathDepth > 0 && len(segments) > t.config.MaxPathDepth {
+		return fmt.Sprintf("path depth %d exceeds %d", len(segments), t.config.MaxPathDepth)
+	}
+
+	if t.config.MaxSegmentRepeats > 0 {
+		counts := make(map[string]int)
+		for _, s := range segments {
+			counts[s]++
+			if counts[s] > t.config.MaxSegmentRepeats {
+				return fmt.Sprintf("path segment %q repeated more than %d times", s, t.config.MaxSegmentRepeats)
+			}
+		}
+	}
+
+	return t.checkHost(u, segments)
+}
+
+// checkHost applies the heuristics that need per-host state.
+func (t *TrapDetector) checkHost(u *url.URL, segments []string) string {
+	host := strings.ToLower(u.Host)
+	template := pathTemplate(segments)
+	urlHash := hashString(u.String())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	space := t.hosts.GetOrCreate(host, func() *hostSpace {
+		return &hostSpace{
+			seen:        make(map[uint64]struct{}),
+			variants:    internal.NewLRU[string, map[uint64]struct{}](maxTemplatesPerHost, 0, nil),
+			windowStart: time.Now(),
+		}
+	})
+
+	// Links we've already accepted are fine; the engine de-duplicates them.
+	if _, known := space.seen[urlHash]; known {
+		return ""
+	}
+
+	if t.config.MaxQueryVariants > 0 && u.RawQuery != "" {
+		variants := space.variants.GetOrCreate(template, func() map[uint64]struct{} {
+			return make(map[uint64]struct{})
+		})
+		queryHash := hashString(canonicalQuery(u.Query()))
+		if _, known := variants[queryHash]; !known {
+			if len(variants) >= t.config.MaxQueryVariants {
+				return fmt.Sprintf("more than %d query combinations for %s%s", t.config.MaxQueryVariants, host, template)
+			}
+			variants[queryHash] = struct{}{}
+		}
+	}
+
+	if t.config.MaxHostGrowth > 0 {
+		if time.Since(space.windowStart) > t.config.GrowthWindow {
+			space.windowStart = time.Now()
+			space.windowCount = 0
+		}
+		if space.windowCount >= t.config.MaxHostGrowth {
+			return fmt.Sprintf("host url space grew by more than %d in %s", t.config.MaxHostGrowth, t.config.GrowthWindow)
+		}
+		space.windowCount++
+	}
+
+	// Forgetting only costs a re-check (and a slot of growth budget) if a link comes back.
+	if len(space.seen) >= maxSeenPerHost {
+		space.seen = make(map[uint64]struct{})
+	}
+	space.seen[urlHash] = struct{}{}
+	return ""
+}
+
+func splitPath(p string) []string {
+	var segments []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// pathTemplate collapses IDs, dates and tokens so that /calendar/2024/01 and
+// /calendar/2031/12 share the same template.
+func pathTemplate(segments []string) string {
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteByte('/')
+		switch {
+		case numericSegment.MatchString(s):
+			b.WriteString("{n}")
+		case tokenSegment.MatchString(s) && strings.ContainsAny(s, "0123456789"):
+			b.WriteString("{id}")
+		default:
+			b.WriteString(s)
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+// canonicalQuery ignores parameter order so ?a=1&b=2 and ?b=2&a=1 count once.
+func canonicalQuery(q url.Values) string {
+	return q.Encode()
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"fmt"
+	"go-crawler/pkg/models"
+	"testing"
+)
+
+type recordingReporter struct {
+	reported []models.Suppression
+}
+
+func (r *recordingReporter) Report(s models.Suppression) {
+	r.reported = append(r.reported, s)
+}
+
+func TestTrapDetector_Check(t *testing.T) {
+	d := NewTrapDetector(TrapConfig{
+		MaxPathDepth:      5,
+		MaxSegmentRepeats: 2,
+		MaxURLLength:      100,
+	}, nil)
+
+	tests := []struct {
+		link    string
+		trapped bool
+	}{
+		{"https://example.com/about", false},
+		{"https://example.com/a/b/a/b/a/b", true},
+		{"https://example.com/1/2/3/4/5/6", true},
+		{"https://example.com/shop;jsessionid=ABC123/cart", true},
+		{"https://example.com/?q=" + fmt.Sprintf("%0200d", 0), true},
+	}
+
+	for _, tt := range tests {
+		reason := d.Check(tt.link)
+		if (reason != "") != tt.trapped {
+			t.Errorf("Check(%q) = %q, expected trapped=%v", tt.link, reason, tt.trapped)
+		}
+	}
+}
+
+func TestTrapDetector_QueryVariantsAndReporting(t *testing.T) {
+	reporter := &recordingReporter{}
+	d := NewTrapDetector(TrapConfig{MaxQueryVariants: 3}, reporter)
+
+	var links []string
+	for i := 0; i < 5; i++ {
+		links = append(links, fmt.Sprintf("https://example.com/search/%d?color=%d&size=1", i, i))
+	}
+	// Different parameter order is the same combination and must not count twice.
+	links = append(links, "https://example.com/search/0?size=1&color=0")
+
+	kept := d.Filter(links)
+	if len(kept) != 4 {
+		t.Errorf("Expected 4 kept links, got %d: %v", len(kept), kept)
+	}
+	if len(reporter.reported) != 2 {
+		t.Fatalf("Expected 2 suppressions to be reported, got %d", len(reporter.reported))
+	}
+	if reporter.reported[0].Source != "trap" || reporter.reported[0].Reason == "" {
+		t.Errorf("Suppression missing source/reason: %+v", reporter.reported[0])
+	}
+}
+
+func TestTrapDetector_HostGrowth(t *testing.T) {
+	d := NewTrapDetector(TrapConfig{MaxHostGrowth: 10}, nil)
+
+	suppressed := 0
+	for i := 0; i < 15; i++ {
+		if d.Check(fmt.Sprintf("https://example.com/page-%d", i)) != "" {
+			suppressed++
+		}
+	}
+	if suppressed != 5 {
+		t.Errorf("Expected 5 URLs suppressed after growth budget, got %d", suppressed)
+	}
+
+	// Other hosts have their own budget.
+	if reason := d.Check("https://other.com/page-0"); reason != "" {
+		t.Errorf("Unexpected suppression on a fresh host: %s", reason)
+	}
+}
+
+func TestTrapDetector_ForgetsIdleHosts(t *testing.T) {
+	d := NewTrapDetector(TrapConfig{MaxHostGrowth: 1, MaxHosts: 2}, nil)
+
+	d.Check("https://a.example/1")
+	d.Check("https://b.example/1")
+	d.Check("https://c.example/1") // Evicts a.example
+	if d.hosts.Len() != 2 {
+		t.Errorf("Expected 2 tracked hosts, got %d", d.hosts.Len())
+	}
+	if reason := d.Check("https://a.example/2"); reason != "" {
+		t.Errorf("Expected an evicted host to start with a fresh budget, got %q", reason)
+	}
+}
@@ -0,0 +1,234 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of WaitCondition.
+const (
+	WaitNetworkIdle      = "network-idle"     // No request in flight for Idle
+	WaitDOMContentLoaded = "domcontentloaded" // The DOMContentLoaded event fired
+	WaitLoad             = "load"             // The load event fired
+	WaitSelector         = "selector"         // An element matching Arg is visible
+	WaitJS               = "js"               // The JS expression Arg is truthy
+)
+
+// WaitTimedOut is what a render records as its wait reason when no condition was met.
+const WaitTimedOut = "timeout"
+
+const defaultWaitTimeout = 10 * time.Second
+
+// defaultWaits is used when neither the Parser nor the domain policy sets any:
+// wait for the load event, like a plain chromedp.Navigate.
+var defaultWaits = []WaitCondition{{Kind: WaitLoad, Timeout: 30 * time.Second}}
+
+// WaitCondition is one thing a render can wait for before the DOM is captured.
+type WaitCondition struct {
+	Kind    string
+	Arg     string        // Selector or JS expression
+	Idle    time.Duration // Quiet period of WaitNetworkIdle
+	Timeout time.Duration // After this the condition is given up on
+}
+
+// ParseWaitCondition parses "kind[=arg][@timeout]", e.g. "network-idle=500ms@15s",
+// "load", "selector=#products .item@5s" or "js=window.__READY__ === true".
+func ParseWaitCondition(spec string) (WaitCondition, error) {
+	spec = strings.TrimSpace(spec)
+	c := WaitCondition{Timeout: defaultWaitTimeout}
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		if timeout, err := time.ParseDuration(spec[i+1:]); err == nil {
+			c.Timeout, spec = timeout, spec[:i]
+		}
+	}
+	c.Kind, c.Arg, _ = strings.Cut(spec, "=")
+	c.Kind = strings.ToLower(strings.TrimSpace(c.Kind))
+	c.Arg = strings.TrimSpace(c.Arg)
+
+	switch c.Kind {
+	case WaitNetworkIdle:
+		c.Idle = 500 * time.Millisecond
+		if c.Arg != "" {
+			idle, err := time.ParseDuration(c.Arg)
+			if err != nil {
+				return c, fmt.Errorf("wait %q: %w", spec, err)
+			}
+			c.Idle, c.Arg = idle, ""
+		}
+	case WaitDOMContentLoaded, WaitLoad:
+		if c.Arg != "" {
+			return c, fmt.Errorf("wait %q takes no argument", spec)
+		}
+	case WaitSelector, WaitJS:
+		if c.Arg == "" {
+			return c, fmt.Errorf("wait %q needs an argument (%s=...)", spec, c.Kind)
+		}
+	default:
+		return c, fmt.Errorf("unknown wait condition %q (use %s, %s, %s, %s or %s)",
+			c.Kind, WaitNetworkIdle, WaitDOMContentLoaded, WaitLoad, WaitSelector, WaitJS)
+	}
+	return c, nil
+}
+
+// ParseWaitConditions parses a ';'-separated list of conditions (see ParseWaitCondition).
+func ParseWaitConditions(specs string) ([]WaitCondition, error) {
+	var conditions []WaitCondition
+	for _, spec := range strings.Split(specs, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		c, err := ParseWaitCondition(spec)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+// String is how the condition is recorded on the page that waited for it.
+func (c WaitCondition) String() string {
+	switch c.Kind {
+	case WaitNetworkIdle:
+		return c.Kind + "=" + c.Idle.String()
+	case WaitSelector, WaitJS:
+		return c.Kind + "=" + c.Arg
+	default:
+		return c.Kind
+	}
+}
+
+// pageActivity follows what a tab is doing during a render, for the event and
+// network idle conditions. Register it with listen before navigating.
+type pageActivity struct {
+	mu               sync.Mutex
+	inFlight         map[network.RequestID]bool
+	lastActivity     time.Time
+	domContentLoaded bool
+	loaded           bool
+}
+
+func newPageActivity() *pageActivity {
+	return &pageActivity{inFlight: make(map[network.RequestID]bool), lastActivity: time.Now()}
+}
+
+func (a *pageActivity) listen(ev interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		a.inFlight[e.RequestID] = true
+	case *network.EventLoadingFinished:
+		delete(a.inFlight, e.RequestID)
+	case *network.EventLoadingFailed:
+		delete(a.inFlight, e.RequestID)
+	case *page.EventDomContentEventFired:
+		a.domContentLoaded = true
+	case *page.EventLoadEventFired:
+		a.loaded = true
+	default:
+		return
+	}
+	a.lastActivity = time.Now()
+}
+
+// idleFor reports whether no request has been in flight for at least d.
+func (a *pageActivity) idleFor(d time.Duration) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.inFlight) == 0 && time.Since(a.lastActivity) >= d
+}
+
+// met reports whether condition c holds right now.
+func (a *pageActivity) met(ctx context.Context, c WaitCondition) bool {
+	switch c.Kind {
+	case WaitNetworkIdle:
+		return a.idleFor(c.Idle)
+	case WaitDOMContentLoaded:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.domContentLoaded || a.loaded
+	case WaitLoad:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.loaded
+	case WaitSelector:
+		selector, _ := json.Marshal(c.Arg)
+		return evaluateTrue(ctx, fmt.Sprintf(scriptVisible, selector))
+	case WaitJS:
+		return evaluateTrue(ctx, "!!("+c.Arg+")")
+	}
+	return false
+}
+
+// scriptVisible checks that the element matching the (JSON-quoted) selector takes up space on the page.
+const scriptVisible = `(() => {
+	const el = document.querySelector(%s);
+	if (!el) return false;
+	const style = getComputedStyle(el), box = el.getBoundingClientRect();
+	return style.visibility !== 'hidden' && style.display !== 'none' && box.width > 0 && box.height > 0;
+})()`
+
+// evaluateTrue runs a predicate in the page. Errors (no document yet, a throwing
+// predicate) count as false, so the condition is simply polled again.
+func evaluateTrue(ctx context.Context, expression string) bool {
+	var ok bool
+	err := chromedp.Evaluate(expression, &ok).Do(ctx)
+	return err == nil && ok
+}
+
+// waitFor polls conditions until one of them is met and records which in result,
+// or records WaitTimedOut once every condition has passed its own timeout.
+func (a *pageActivity) waitFor(conditions []WaitCondition, result *string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		start := time.Now()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			pending := false
+			for _, c := range conditions {
+				if time.Since(start) >= c.Timeout {
+					continue
+				}
+				pending = true
+				if a.met(ctx, c) {
+					*result = c.String()
+					return nil
+				}
+			}
+			if !pending {
+				*result = WaitTimedOut
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// navigate starts loading targetURL without waiting for the load event like
+// chromedp.Navigate does; the wait conditions decide when the page is ready.
+func navigate(targetURL string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, _, errorText, err := page.Navigate(targetURL).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if errorText != "" {
+			return fmt.Errorf("page load error %s", errorText)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"context"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"testing"
+	"time"
+)
+
+func TestParseWaitConditions(t *testing.T) {
+	conditions, err := ParseWaitConditions("network-idle=800ms@15s; load; selector=a[href^='mailto:x@y'] ; js=window.ready === true@2s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []WaitCondition{
+		{Kind: WaitNetworkIdle, Idle: 800 * time.Millisecond, Timeout: 15 * time.Second},
+		{Kind: WaitLoad, Timeout: defaultWaitTimeout},
+		{Kind: WaitSelector, Arg: "a[href^='mailto:x@y']", Timeout: defaultWaitTimeout},
+		{Kind: WaitJS, Arg: "window.ready === true", Timeout: 2 * time.Second},
+	}
+	if len(conditions) != len(want) {
+		t.Fatalf("Expected %d conditions, got %+v", len(want), conditions)
+	}
+	for i := range want {
+		if conditions[i] != want[i] {
+			t.Errorf("Condition %d: expected %+v, got %+v", i, want[i], conditions[i])
+		}
+	}
+
+	for _, bad := range []string{"selector", "load=now", "network-idle=soon", "sleep=1s"} {
+		if _, err := ParseWaitCondition(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPageActivity_WaitFor(t *testing.T) {
+	activity := newPageActivity()
+	activity.listen(&network.EventRequestWillBeSent{RequestID: "1"})
+	conditions := []WaitCondition{
+		{Kind: WaitNetworkIdle, Idle: 50 * time.Millisecond, Timeout: time.Second},
+		{Kind: WaitLoad, Timeout: 150 * time.Millisecond},
+	}
+
+	// The request still in flight keeps the network busy, and load never fires.
+	var result string
+	start := time.Now()
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		activity.listen(&network.EventLoadingFinished{RequestID: "1"})
+	}()
+	if err := activity.waitFor(conditions, &result).Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if result != "network-idle=50ms" || time.Since(start) < 350*time.Millisecond {
+		t.Errorf("Expected network idle to end the wait after the request finished, got %q after %s", result, time.Since(start))
+	}
+
+	activity.listen(&page.EventLoadEventFired{})
+	if err := activity.waitFor(conditions[1:], &result).Do(context.Background()); err != nil || result != WaitLoad {
+		t.Errorf("Expected the load event to end the wait, got %q (%v)", result, err)
+	}
+
+	if err := activity.waitFor([]WaitCondition{{Kind: WaitDOMContentLoaded}}, &result).Do(context.Background()); err != nil || result != WaitTimedOut {
+		t.Errorf("Expected a condition with no time left to time out, got %q (%v)", result, err)
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size- and age-bounded map. Once it holds 'size' entries the least
+// recently used one is evicted, and entries untouched for 'ttl' are evicted
+// when they are next seen. Evicted entries are handed to onEvict (outside the lock).
+type LRU[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	onEvict func(key K, value V)
+	items   map[K]*list.Element
+	order   *list.List // Front = most recently used
+}
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	touched time.Time
+}
+
+// NewLRU creates a cache. size <= 0 means unbounded, ttl <= 0 means no expiry,
+// onEvict may be nil.
+func NewLRU[K comparable, V any](size int, ttl time.Duration, onEvict func(key K, value V)) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:    size,
+		ttl:     ttl,
+		onEvict: onEvict,
+		items:   make(map[K]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the value for key and marks it as recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	value, ok, evicted := c.get(key)
+	c.mu.Unlock()
+	c.notify(evicted)
+	return value, ok
+}
+
+// Put inserts or replaces the value for key.
+func (c *LRU[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	evicted := c.put(key, value)
+	c.mu.Unlock()
+	c.notify(evicted)
+}
+
+// GetOrCreate returns the value for key, atomically creating it with create() if missing.
+func (c *LRU[K, V]) GetOrCreate(key K, create func() V) V {
+	c.mu.Lock()
+	value, ok, evicted := c.get(key)
+	if !ok {
+		value = create()
+		evicted = append(evicted, c.put(key, value)...)
+	}
+	c.mu.Unlock()
+	c.notify(evicted)
+	return value
+}
+
+// Delete removes key without calling onEvict.
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of entries, including ones that have expired but not been swept yet.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Range calls fn for every entry, most recently used first. fn must not call back into the cache.
+func (c *LRU[K, V]) Range(fn func(key K, value V)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*lruEntry[K, V])
+		fn(e.key, e.value)
+	}
+}
+
+func (c *LRU[K, V]) get(key K) (value V, ok bool, evicted []*lruEntry[K, V]) {
+	el, exists := c.items[key]
+	if !exists {
+		return value, false, nil
+	}
+	e := el.Value.(*lruEntry[K, V])
+	if c.expired(e) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return value, false, []*lruEntry[K, V]{e}
+	}
+	e.touched = time.Now()
+	c.order.MoveToFront(el)
+	return e.value, true, nil
+}
+
+func (c *LRU[K, V]) put(key K, value V) []*lruEntry[K, V] {
+	if el, exists := c.items[key]; exists {
+		e := el.Value.(*lruEntry[K, V])
+		e.value, e.touched = value, time.Now()
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, touched: time.Now()})
+
+	// Sweep from the cold end: anything over capacity or idle past the TTL goes.
+	var evicted []*lruEntry[K, V]
+	for el := c.order.Back(); el != nil && el != c.order.Front(); el = c.order.Back() {
+		e := el.Value.(*lruEntry[K, V])
+		if !(c.size > 0 && c.order.Len() > c.size) && !c.expired(e) {
+			break
+		}
+		c.order.Remove(el)
+		delete(c.items, e.key)
+		evicted = append(evicted, e)
+	}
+	return evicted
+}
+
+func (c *LRU[K, V]) expired(e *lruEntry[K, V]) bool {
+	return c.ttl > 0 && time.Since(e.touched) > c.ttl
+}
+
+func (c *LRU[K, V]) notify(evicted []*lruEntry[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		c.onEvict(e.key, e.value)
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	c := NewLRU(2, 0, func(key string, _ int) { evicted = append(evicted, key) })
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a") // b is now the coldest
+	c.Put("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Expected a=1 to survive, got %d, %v", v, ok)
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("Expected onEvict(b), got %v", evicted)
+	}
+}
+
+func TestLRU_ExpiresIdleEntries(t *testing.T) {
+	var evicted []string
+	c := NewLRU(0, 20*time.Millisecond, func(key string, _ int) { evicted = append(evicted, key) })
+
+	c.Put("old", 1)
+	time.Sleep(30 * time.Millisecond)
+	c.Put("new", 2)
+
+	if c.Len() != 1 || len(evicted) != 1 || evicted[0] != "old" {
+		t.Errorf("Expected the idle entry to be swept, len=%d evicted=%v", c.Len(), evicted)
+	}
+	if v := c.GetOrCreate("new", func() int { return 0 }); v != 2 {
+		t.Errorf("Expected GetOrCreate to return the existing value, got %d", v)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"database/sql"
+	"go-crawler/pkg/models"
+)
+
+// CookieStore implements crawler.CookieStore on the 'cookies' table.
+type CookieStore struct {
+	*Storage
+}
+
+func (s *CookieStore) LoadCookies(site string) ([]models.StoredCookie, error) {
+	rows, err := s.db.Query(`
+		SELECT host, name, value, domain, path, expires, secure, http_only
+		FROM cookies WHERE site = $1`, site,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cookies []models.StoredCookie
+	for rows.Next() {
+		c := models.StoredCookie{Site: site}
+		var expires sql.NullTime
+		if err := rows.Scan(&c.Host, &c.Name, &c.Value, &c.Domain, &c.Path, &expires, &c.Secure, &c.HttpOnly); err != nil {
+			return nil, err
+		}
+		c.Expires = expires.Time
+		cookies = append(cookies, c)
+	}
+	return cookies, rows.Err()
+}
+
+// SaveCookies replaces everything stored for the site.
+func (s *CookieStore) SaveCookies(site string, cookies []models.StoredCookie) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM cookies WHERE site = $1`, site); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO cookies (site, host, name, value, domain, path, expires, secure, http_only)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range cookies {
+		expires := sql.NullTime{Time: c.Expires, Valid: !c.Expires.IsZero()}
+		if _, err := stmt.Exec(site, c.Host, c.Name, c.Value, c.Domain, c.Path, expires, c.Secure, c.HttpOnly); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"go-crawler/pkg/models"
+)
+
+// DynamicStore implements crawler.DynamicStore on the 'dynamic_domains' table.
+type DynamicStore struct {
+	*Storage
+}
+
+func (s *DynamicStore) LoadDynamicHosts() ([]models.DynamicHost, error) {
+	rows, err := s.db.Query(`SELECT host, marked_at, expires_at, hits FROM dynamic_domains`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hosts []models.DynamicHost
+	for rows.Next() {
+		var h models.DynamicHost
+		if err := rows.Scan(&h.Host, &h.MarkedAt, &h.ExpiresAt, &h.Hits); err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, rows.Err()
+}
+
+func (s *DynamicStore) SaveDynamicHost(h models.DynamicHost) error {
+	_, err := s.db.Exec(`
+		INSERT INTO dynamic_domains (host, marked_at, expires_at, hits)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (host) DO UPDATE
+		SET marked_at = EXCLUDED.marked_at, expires_at = EXCLUDED.expires_at, hits = EXCLUDED.hits`,
+		h.Host, h.MarkedAt, h.ExpiresAt, h.Hits,
+	)
+	return err
+}
+
+func (s *DynamicStore) DeleteDynamicHost(host string) error {
+	_, err := s.db.Exec(`DELETE FROM dynamic_domains WHERE host = $1`, host)
+	return err
+}
+
+func (s *DynamicStore) LoadDynamicHost(host string) (models.DynamicHost, bool, error) {
+	h := models.DynamicHost{Host: host}
+	err := s.db.QueryRow(`SELECT marked_at, expires_at, hits FROM dynamic_domains WHERE host = $1`, host).
+		Scan(&h.MarkedAt, &h.ExpiresAt, &h.Hits)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h, false, nil
+	}
+	return h, err == nil, err
+}
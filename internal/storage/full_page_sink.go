@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"database/sql"
+	"errors"
 	"go-crawler/pkg/models"
 	"log"
 	"time"
@@ -11,6 +13,66 @@ type PageSink struct {
 	*Storage
 }
 
+// Recrawled pages replace the stored copy; a 304 only refreshes the crawl time
+// (and validators, if the server sent new ones).
+const (
+	upsertPage = `
+		INSERT INTO pages (url, title, content_text, status_code, load_time_ms, crawled_at, etag, last_modified, truncated, waited_for)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''))
+		ON CONFLICT (url) DO UPDATE
+		SET title = EXCLUDED.title, content_text = EXCLUDED.content_text, status_code = EXCLUDED.status_code,
+		    load_time_ms = EXCLUDED.load_time_ms, crawled_at = EXCLUDED.crawled_at,
+		    etag = EXCLUDED.etag, last_modified = EXCLUDED.last_modified, truncated = EXCLUDED.truncated,
+		    waited_for = EXCLUDED.waited_for`
+	touchPage = `
+		UPDATE pages SET crawled_at = $2,
+		    etag = COALESCE(NULLIF($3, ''), etag), last_modified = COALESCE(NULLIF($4, ''), last_modified)
+		WHERE url = $1`
+	deleteLinks = `DELETE FROM page_links WHERE source_url = $1`
+	insertLinks = `
+		INSERT INTO page_links (source_url, target_url)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING`
+	deleteAPIResponses = `DELETE FROM api_responses WHERE page_url = $1`
+	insertAPIResponse  = `
+		INSERT INTO api_responses (page_url, url, method, status_code, content_type, body)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+)
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// saveLinks replaces the links stored for the page with those followed on this crawl.
+func saveLinks(db execer, p models.PageData) error {
+	if _, err := db.Exec(deleteLinks, p.URL); err != nil {
+		return err
+	}
+	if len(p.OutboundLinks) == 0 {
+		return nil
+	}
+	_, err := db.Exec(insertLinks, p.URL, p.OutboundLinks)
+	return err
+}
+
+// saveAPIResponses replaces the page's captured API responses with those of this
+// render, so a render that captured nothing clears them. Pages fetched without
+// capture (static, or capture off) keep what an earlier render stored.
+func saveAPIResponses(db execer, p models.PageData) error {
+	if !p.APICaptured {
+		return nil
+	}
+	if _, err := db.Exec(deleteAPIResponses, p.URL); err != nil {
+		return err
+	}
+	for _, r := range p.APIResponses {
+		if _, err := db.Exec(insertAPIResponse, p.URL, r.URL, r.Method, r.StatusCode, r.ContentType, r.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *PageSink) Save(batch []models.PageData) error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -18,25 +80,40 @@ func (s *PageSink) Save(batch []models.PageData) error {
 	}
 	defer tx.Rollback()
 
-	// Prepare statement (adapted from your original internal/storage/storage.go)
-	stmt, err := tx.Prepare(`
-		INSERT INTO pages (url, title, content_text, status_code, load_time_ms, crawled_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (url) DO NOTHING`)
+	upsert, err := tx.Prepare(upsertPage)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
+	defer upsert.Close()
+	touch, err := tx.Prepare(touchPage)
+	if err != nil {
+		return err
+	}
+	defer touch.Close()
 
 	for _, p := range batch {
-		_, err := stmt.Exec(
-			p.URL,
-			p.Title,
-			p.TextContent,
-			p.StatusCode,
-			p.LoadTime.Milliseconds(),
-			time.Now(),
-		)
+		if p.NotModified {
+			_, err = touch.Exec(p.URL, time.Now(), p.ETag, p.LastModified)
+		} else {
+			_, err = upsert.Exec(
+				p.URL,
+				p.Title,
+				p.TextContent,
+				p.StatusCode,
+				p.LoadTime.Milliseconds(),
+				time.Now(),
+				p.ETag,
+				p.LastModified,
+				p.Truncated,
+				p.WaitedFor,
+			)
+			if err == nil {
+				err = saveLinks(tx, p)
+			}
+			if err == nil {
+				err = saveAPIResponses(tx, p)
+			}
+		}
 		if err != nil {
 			tx.Rollback()
 			s.saveIndividually(batch)
@@ -49,14 +126,52 @@ func (s *PageSink) Save(batch []models.PageData) error {
 
 func (s *PageSink) saveIndividually(batch []models.PageData) {
 	for _, p := range batch {
-		_, err := s.db.Exec(`
-			INSERT INTO pages (url, title, content_text, status_code, load_time_ms, crawled_at)
-			VALUES ($1, $2, $3, $4, $5, $6)
-			ON CONFLICT (url) DO NOTHING`,
-			p.URL, p.Title, p.TextContent, p.StatusCode, p.LoadTime.Milliseconds(), time.Now(),
-		)
+		var err error
+		if p.NotModified {
+			_, err = s.db.Exec(touchPage, p.URL, time.Now(), p.ETag, p.LastModified)
+		} else {
+			_, err = s.db.Exec(upsertPage,
+				p.URL, p.Title, p.TextContent, p.StatusCode, p.LoadTime.Milliseconds(), time.Now(), p.ETag, p.LastModified, p.Truncated, p.WaitedFor,
+			)
+			if err == nil {
+				err = saveLinks(s.db, p)
+			}
+			if err == nil {
+				err = saveAPIResponses(s.db, p)
+			}
+		}
 		if err != nil {
 			log.Printf("Skipping page %s: %v", p.URL, err)
 		}
 	}
 }
+
+// LoadValidators implements crawler.ValidatorStore.
+func (s *PageSink) LoadValidators(url string) (etag, lastModified string, err error) {
+	err = s.db.QueryRow(`
+		SELECT COALESCE(etag, ''), COALESCE(last_modified, '') FROM pages WHERE url = $1`, url,
+	).Scan(&etag, &lastModified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+	return etag, lastModified, err
+}
+
+// LoadLinks implements crawler.LinkStore.
+func (s *PageSink) LoadLinks(url string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT target_url FROM page_links WHERE source_url = $1`, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"go-crawler/pkg/models"
+	"time"
+)
+
+// HostStateStore implements crawler.HostStateStore on the 'host_state' table.
+type HostStateStore struct {
+	*Storage
+}
+
+func (s *HostStateStore) LoadHostState(host string) (models.HostState, bool, error) {
+	h := models.HostState{Host: host}
+	var delayMs, coolOffMs int64
+	var pausedUntil, openUntil sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT delay_ms, paused_until, circuit_open, failures, cool_off_ms, open_until
+		FROM host_state WHERE host = $1`, host,
+	).Scan(&delayMs, &pausedUntil, &h.CircuitOpen, &h.Failures, &coolOffMs, &openUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h, false, nil
+	}
+	if err != nil {
+		return h, false, err
+	}
+	h.Delay = time.Duration(delayMs) * time.Millisecond
+	h.CoolOff = time.Duration(coolOffMs) * time.Millisecond
+	h.PausedUntil, h.OpenUntil = pausedUntil.Time, openUntil.Time
+	return h, true, nil
+}
+
+func (s *HostStateStore) SaveHostState(h models.HostState) error {
+	pausedUntil := sql.NullTime{Time: h.PausedUntil, Valid: !h.PausedUntil.IsZero()}
+	openUntil := sql.NullTime{Time: h.OpenUntil, Valid: !h.OpenUntil.IsZero()}
+	_, err := s.db.Exec(`
+		INSERT INTO host_state (host, delay_ms, paused_until, circuit_open, failures, cool_off_ms, open_until, saved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (host) DO UPDATE
+		SET delay_ms = EXCLUDED.delay_ms, paused_until = EXCLUDED.paused_until, circuit_open = EXCLUDED.circuit_open,
+		    failures = EXCLUDED.failures, cool_off_ms = EXCLUDED.cool_off_ms, open_until = EXCLUDED.open_until,
+		    saved_at = EXCLUDED.saved_at`,
+		h.Host, h.Delay.Milliseconds(), pausedUntil, h.CircuitOpen, h.Failures, h.CoolOff.Milliseconds(), openUntil,
+	)
+	return err
+}
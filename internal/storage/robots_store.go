@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"go-crawler/pkg/models"
+)
+
+// RobotsStore implements crawler.RobotsStore on the 'robots_txt' table.
+type RobotsStore struct {
+	*Storage
+}
+
+func (s *RobotsStore) LoadRobots(origin string) (models.RobotsRecord, bool, error) {
+	record := models.RobotsRecord{Origin: origin}
+	err := s.db.QueryRow(`
+		SELECT status_code, body, fetched_at FROM robots_txt WHERE origin = $1`, origin,
+	).Scan(&record.StatusCode, &record.Body, &record.FetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, false, nil
+	}
+	if err != nil {
+		return record, false, err
+	}
+	return record, true, nil
+}
+
+func (s *RobotsStore) SaveRobots(record models.RobotsRecord) error {
+	_, err := s.db.Exec(`
+		INSERT INTO robots_txt (origin, status_code, body, fetched_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (origin) DO UPDATE
+		SET status_code = EXCLUDED.status_code, body = EXCLUDED.body, fetched_at = EXCLUDED.fetched_at`,
+		record.Origin, record.StatusCode, record.Body, record.FetchedAt,
+	)
+	return err
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"database/sql"
+	"go-crawler/pkg/models"
+)
+
+// SitemapSink implements crawler.SitemapSink, saving entries to the 'sitemap_urls' table.
+type SitemapSink struct {
+	*Storage
+}
+
+func (s *SitemapSink) Save(batch []models.SitemapEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO sitemap_urls (url, sitemap_url, lastmod, priority)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (url) DO UPDATE
+		SET sitemap_url = EXCLUDED.sitemap_url, lastmod = EXCLUDED.lastmod, priority = EXCLUDED.priority`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range batch {
+		lastMod := sql.NullTime{Time: e.LastMod, Valid: !e.LastMod.IsZero()}
+		if _, err := stmt.Exec(e.URL, e.Sitemap, lastMod, e.Priority); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
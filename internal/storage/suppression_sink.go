@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"go-crawler/pkg/models"
+	"log"
+	"sync"
+)
+
+// suppressionBatch is how many suppressions are buffered before Report writes them.
+const suppressionBatch = 200
+
+// SuppressionLog implements crawler.SuppressionReporter by writing every
+// suppressed URL to the 'crawl_suppressions' table. Reports are buffered and
+// written in batches; call Flush periodically and before exiting.
+type SuppressionLog struct {
+	*Storage
+
+	mu      sync.Mutex
+	pending []models.Suppression
+}
+
+func (s *SuppressionLog) Report(sup models.Suppression) {
+	s.mu.Lock()
+	s.pending = append(s.pending, sup)
+	full := len(s.pending) >= suppressionBatch
+	s.mu.Unlock()
+
+	if full {
+		s.Flush()
+	}
+}
+
+// Flush writes the buffered suppressions in one transaction.
+func (s *SuppressionLog) Flush() {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if err := s.save(batch); err != nil {
+		log.Printf("Failed to record %d suppressions: %v", len(batch), err)
+	}
+}
+
+func (s *SuppressionLog) save(batch []models.Suppression) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.Prepare(`
+		INSERT INTO crawl_suppressions (url, source, reason, suppressed_at)
+		VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, sup := range batch {
+		if _, err := insert.Exec(sup.URL, sup.Source, sup.Reason, sup.At); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
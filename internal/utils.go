@@ -20,3 +20,10 @@ func (s *SafeMap) Contains(url string) bool {
 	s.v[url] = true
 	return false // New URL
 }
+
+// Seen reports whether url was already visited, without marking it.
+func (s *SafeMap) Seen(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.v[url]
+}
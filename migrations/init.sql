@@ -8,6 +8,16 @@ CREATE TABLE IF NOT EXISTS pages (
                                  crawled_at TIMESTAMP DEFAULT NOW()
 );
 
+-- Response validators for conditional recrawls (If-None-Match / If-Modified-Since)
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS etag TEXT;
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS last_modified TEXT;
+
+-- Pages cut at MAX_DECODED_BYTES (rejected ones go to crawl_suppressions instead)
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS truncated BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Which WAIT_FOR / policy wait condition ended a Chrome render ('timeout' if none; NULL for static pages)
+ALTER TABLE pages ADD COLUMN IF NOT EXISTS waited_for TEXT;
+
 CREATE TABLE IF NOT EXISTS page_links (
                                       source_url TEXT NOT NULL,
                                       target_url TEXT NOT NULL,
@@ -32,4 +42,80 @@ CREATE TABLE IF NOT EXISTS product_queue (
                              );
 
 -- Optional: Create an index on status if you plan to query by "pending" often
-CREATE INDEX IF NOT EXISTS idx_product_queue_status ON product_queue(status);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_product_queue_status ON product_queue(status);
+
+-- URLs that were dropped before being queued or fetched, kept for review
+CREATE TABLE IF NOT EXISTS crawl_suppressions (
+                                          id SERIAL PRIMARY KEY,
+                                          url TEXT NOT NULL,
+                                          source VARCHAR(50) NOT NULL,
+                                          reason TEXT NOT NULL,
+                                          suppressed_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_suppressions_source ON crawl_suppressions(source);
+
+-- Raw robots.txt responses, so restarts don't refetch every origin
+CREATE TABLE IF NOT EXISTS robots_txt (
+                                  origin TEXT PRIMARY KEY,
+                                  status_code INT NOT NULL,
+                                  body BYTEA,
+                                  fetched_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Every URL listed in a sitemap: the ground truth for coverage reports
+CREATE TABLE IF NOT EXISTS sitemap_urls (
+                                    url TEXT PRIMARY KEY,
+                                    sitemap_url TEXT NOT NULL,
+                                    lastmod TIMESTAMP WITH TIME ZONE,
+                                    priority REAL NOT NULL DEFAULT 0.5,
+                                    discovered_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Hosts that need headless Chrome, learned from MarkDynamic and loaded at startup
+CREATE TABLE IF NOT EXISTS dynamic_domains (
+                                       host TEXT PRIMARY KEY,
+                                       marked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                       expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+                                       hits INT NOT NULL DEFAULT 1
+);
+
+-- Learned pace and circuit breaker of hosts evicted from memory (HOST_STATE_MAX / HOST_STATE_TTL)
+CREATE TABLE IF NOT EXISTS host_state (
+                                  host TEXT PRIMARY KEY,
+                                  delay_ms BIGINT NOT NULL,
+                                  paused_until TIMESTAMP WITH TIME ZONE,
+                                  circuit_open BOOLEAN NOT NULL DEFAULT FALSE,
+                                  failures INT NOT NULL DEFAULT 0,
+                                  cool_off_ms BIGINT NOT NULL DEFAULT 0,
+                                  open_until TIMESTAMP WITH TIME ZONE,
+                                  saved_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Cookies set by crawled sites (and policy cookies once sent), so sessions survive restarts
+CREATE TABLE IF NOT EXISTS cookies (
+                               site TEXT NOT NULL,
+                               host TEXT NOT NULL,
+                               name TEXT NOT NULL,
+                               domain TEXT NOT NULL DEFAULT '',
+                               path TEXT NOT NULL DEFAULT '/',
+                               value TEXT NOT NULL,
+                               expires TIMESTAMP WITH TIME ZONE,
+                               secure BOOLEAN NOT NULL DEFAULT FALSE,
+                               http_only BOOLEAN NOT NULL DEFAULT FALSE,
+                               PRIMARY KEY (site, host, name, domain, path)
+);
+
+-- XHR/fetch responses captured while rendering pages (CAPTURE_API); replaced on every render with capture on
+CREATE TABLE IF NOT EXISTS api_responses (
+                                     id SERIAL PRIMARY KEY,
+                                     page_url TEXT NOT NULL,
+                                     url TEXT NOT NULL,
+                                     method VARCHAR(10) NOT NULL DEFAULT 'GET',
+                                     status_code INT,
+                                     content_type TEXT,
+                                     body TEXT NOT NULL,
+                                     captured_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_responses_page_url ON api_responses(page_url);
@@ -1,14 +1,127 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type PageData struct {
 	URL           string
+	FetchedURL    string // Set when URL was replaced by the canonical URL: where the fetch really ended up
 	Title         string
 	TextContent   string
 	StatusCode    int
 	LoadTime      time.Duration
 	OutboundLinks []string
+	Charset       string        // Encoding the page was served in; TextContent is always UTF-8
+	Truncated     bool          // The body was cut at the size limit (see MAX_DECODED_BYTES)
+	Redirects     []Redirect    // Hops taken to reach URL, which is the final URL; empty if none
+	WaitedFor     string        // Rendered pages only: the wait condition that ended the render, or "timeout"
+	APIResponses  []APIResponse // XHR/fetch responses captured during the render (see CAPTURE_API)
+	APICaptured   bool          // Rendered with capture on: APIResponses replaces earlier captures, even when empty
+
+	// Robots directives found in <meta name="robots">, <link rel="canonical">,
+	// rel="nofollow" links and the X-Robots-Tag header.
+	CanonicalURL  string
+	NoIndex       bool
+	NoFollow      bool
+	NofollowLinks []string
+
+	// Response validators, sent back as If-None-Match / If-Modified-Since on recrawl.
+	// NotModified means the server answered 304: only the crawl time changed.
+	ETag         string
+	LastModified string
+	NotModified  bool
+}
+
+// APIResponse is an XHR/fetch response a page made while it was rendered.
+type APIResponse struct {
+	URL         string
+	Method      string
+	StatusCode  int
+	ContentType string
+	Body        string
+}
+
+// Decode unmarshals the JSON body into v.
+func (r APIResponse) Decode(v any) error {
+	return json.Unmarshal([]byte(r.Body), v)
+}
+
+// Redirect is one hop of a redirect chain: URL answered with StatusCode (301, 302...).
+// StatusCode is 0 when a browser render didn't report it.
+type Redirect struct {
+	URL        string
+	StatusCode int
+}
+
+// FinalURL is where the fetch ended up after redirects. The page is stored under
+// URL, which differs from it when a canonical URL was honoured.
+func (p PageData) FinalURL() string {
+	if p.FetchedURL != "" {
+		return p.FetchedURL
+	}
+	return p.URL
+}
+
+// Suppression records a URL the crawler chose not to queue or fetch, and why.
+type Suppression struct {
+	URL    string
+	Source string // Which component suppressed it (e.g. "trap")
+	Reason string
+	At     time.Time
+}
+
+// StoredCookie is a cookie persisted across restarts. Site is the registrable
+// domain it is grouped under, Host the host that set it; an empty Domain means a
+// host-only cookie. A zero Expires means a session cookie.
+type StoredCookie struct {
+	Site     string
+	Host     string
+	Name     string
+	Value    string
+	Domain   string
+	Path     string
+	Expires  time.Time
+	Secure   bool
+	HttpOnly bool
+}
+
+// RobotsRecord is a raw robots.txt response for one origin (scheme://host:port).
+// StatusCode 0 means the server was unreachable.
+type RobotsRecord struct {
+	Origin     string
+	StatusCode int
+	Body       []byte
+	FetchedAt  time.Time
+}
+
+// SitemapEntry is one <url> from a sitemap. LastMod is zero when the sitemap omits it.
+type SitemapEntry struct {
+	URL      string
+	Sitemap  string
+	LastMod  time.Time
+	Priority float64
+}
+
+// DynamicHost is a host that needed headless Chrome, and until when we assume it still does.
+type DynamicHost struct {
+	Host      string
+	MarkedAt  time.Time
+	ExpiresAt time.Time
+	Hits      int
+}
+
+// HostState is the learned pace and circuit breaker of a host, saved when the
+// host is evicted from memory. OpenUntil only matters while CircuitOpen.
+type HostState struct {
+	Host        string
+	Delay       time.Duration
+	PausedUntil time.Time
+	CircuitOpen bool
+	Failures    int
+	CoolOff     time.Duration
+	OpenUntil   time.Time
 }
 
 type URLQueue struct {